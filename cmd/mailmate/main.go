@@ -6,15 +6,105 @@ import (
 	"os"
 	"strings"
 
-	"mailmate/internal/mailer/outlookole"
+	"mailmate/internal/config"
+	"mailmate/internal/mailer"
 	"mailmate/internal/models"
 	"mailmate/internal/runner"
+
+	// Backends register themselves with the mailer package.
+	_ "mailmate/internal/mailer/emlfile"
+	_ "mailmate/internal/mailer/graph"
+	_ "mailmate/internal/mailer/imapdraft"
+	_ "mailmate/internal/mailer/maildir"
+	_ "mailmate/internal/mailer/outlookole"
+	_ "mailmate/internal/mailer/smtpmail"
+	_ "mailmate/internal/mailer/stdout"
 )
 
+// stringList is a flag.Value collecting every occurrence of a repeatable flag.
+type stringList []string
+
+// String implements flag.Value.
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+// Set implements flag.Value.
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// loadConfig reads the configuration file given by --config, or the default one if it exists.
+func loadConfig(configPath string) (*config.Config, error) {
+	path := configPath
+	if path == "" {
+		path = config.DefaultPath()
+	}
+	return config.Load(path, configPath != "")
+}
+
+// resolveBackend returns the name of the backend to use, with the following priority:
+// --backend flag > MAILMATE_BACKEND environment variable > config file > "outlook".
+func resolveBackend(backendFlag string, cfg *config.Config) string {
+	if backendFlag != "" {
+		return backendFlag
+	}
+	if backend := os.Getenv("MAILMATE_BACKEND"); backend != "" {
+		return backend
+	}
+	if cfg.Backend != "" {
+		return cfg.Backend
+	}
+	return "outlook"
+}
+
+// newSender creates the EmailSender for the backend resolved by resolveBackend.
+// overrides are backend options set from CLI flags, applied on top of the config file.
+func newSender(backendFlag string, cfg *config.Config, overrides map[string]any) (mailer.EmailSender, error) {
+	backend := resolveBackend(backendFlag, cfg)
+	// --format is an option of the stdout backend only
+	if _, ok := overrides["format"]; ok && backend != "stdout" {
+		return nil, fmt.Errorf("--format is only supported by the stdout backend (--dry-run), not %q", backend)
+	}
+	return mailer.NewSender(backend, config.WithOverrides(cfg.BackendDecoder(backend), overrides))
+}
+
+// runInit implements the "init" command, which scaffolds a templates directory and a config file.
+func runInit(args []string) error {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	dir := flags.String("dir", "", "Templates directory to create (default: $MAILMATE_TEMPLATES_DIR or \"templates\" next to the config file)")
+	configPath := flags.String("config", "", "Path of the configuration file to create (default: $MAILMATE_CONFIG or user config dir)")
+	force := flags.Bool("force", false, "Overwrite the existing starter templates")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mailmate init [--dir <templates dir>] [--config <path>] [--force]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	path := *configPath
+	if path == "" {
+		path = config.DefaultPath()
+	}
+	return runner.Init(*dir, path, *force)
+}
+
 func main() {
-	// Pre-process args to handle --template and --kv without values
+	// "mailmate init" scaffolds the templates directory and the config file
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		if err := runInit(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Pre-process args to handle --template, --kv and --backend without values
 	var templateExplicitlyProvided bool
 	var kvExplicitlyProvided bool
+	var backendExplicitlyProvided bool
 
 	for i := 1; i < len(os.Args); i++ {
 		arg := os.Args[i]
@@ -48,17 +138,53 @@ func main() {
 				i++ // Skip the inserted empty string
 			}
 		}
+
+		// Handle --backend
+		if arg == "--backend" || arg == "-backend" {
+			backendExplicitlyProvided = true
+			// Check if next arg exists and doesn't start with "-"
+			if i+1 >= len(os.Args) || strings.HasPrefix(os.Args[i+1], "-") {
+				// No value provided, insert empty string
+				args := make([]string, 0, len(os.Args)+1)
+				args = append(args, os.Args[:i+1]...)
+				args = append(args, "")
+				args = append(args, os.Args[i+1:]...)
+				os.Args = args
+				i++ // Skip the inserted empty string
+			}
+		}
 	}
 
 	// Parse CLI flags
 	noPreview := flag.Bool("no-preview", false, "Skip the HTML preview step and open Outlook directly")
-	template := flag.String("template", "", "Template path (skip TUI selection)")
-	to := flag.String("to", "", "Recipient email address")
-	cc := flag.String("cc", "", "Carbon copy recipient email address")
-	bcc := flag.String("bcc", "", "Blind carbon copy recipient email address")
+	template := flag.String("template", "", "Template path, or path relative to the templates directories (skip TUI selection)")
+	var to, cc, bcc, addTo, addCc, addBcc stringList
+	flag.Var(&to, "to", "Recipient email address, replaces the template recipients (repeatable)")
+	flag.Var(&cc, "cc", "Carbon copy recipient email address, replaces the template value (repeatable)")
+	flag.Var(&bcc, "bcc", "Blind carbon copy recipient email address, replaces the template value (repeatable)")
+	flag.Var(&addTo, "add-to", "Recipient email address added to the template recipients (repeatable)")
+	flag.Var(&addCc, "add-cc", "Carbon copy recipient email address added to the template value (repeatable)")
+	flag.Var(&addBcc, "add-bcc", "Blind carbon copy recipient email address added to the template value (repeatable)")
+	var attach stringList
+	flag.Var(&attach, "attach", "File to attach to the email (repeatable)")
+	noTemplateRecipients := flag.Bool("no-template-recipients", false, "Ignore the recipients defined in the template")
 	kv := flag.String("kv", "", "Key-value pairs for template variables (key1='value';key2='value2')")
+	backend := flag.String("backend", "", "Email backend to use (outlook, smtp, eml, ...). Empty lists available backends")
+	configPath := flag.String("config", "", "Path to the configuration file (default: $MAILMATE_CONFIG or user config dir)")
+	dryRun := flag.Bool("dry-run", false, "Print the draft instead of creating it (same as --backend stdout)")
+	format := flag.String("format", "", "Output format of the stdout backend (text, json)")
 	flag.Parse()
 
+	// --backend flag provided but empty: list available backends and exit
+	if backendExplicitlyProvided && *backend == "" {
+		fmt.Println("Available backends:")
+		for _, name := range mailer.Backends() {
+			fmt.Printf("  - %s\n", name)
+		}
+		fmt.Println("\nUsage: --backend <name>")
+		return
+	}
+
 	// Determine if flags were explicitly provided
 	var templatePtr *string
 	if templateExplicitlyProvided {
@@ -71,16 +197,47 @@ func main() {
 	}
 
 	options := models.Options{
-		NoPreview: *noPreview,
-		Template:  templatePtr,
-		To:        *to,
-		Cc:        *cc,
-		Bcc:       *bcc,
-		KV:        kvPtr,
+		NoPreview:            *noPreview,
+		Template:             templatePtr,
+		To:                   to,
+		Cc:                   cc,
+		Bcc:                  bcc,
+		KV:                   kvPtr,
+		AddTo:                addTo,
+		AddCc:                addCc,
+		AddBcc:               addBcc,
+		NoTemplateRecipients: *noTemplateRecipients,
+		Attachments:          attach,
+	}
+
+	// --dry-run is a shortcut for the stdout backend
+	if *dryRun {
+		if *backend != "" && *backend != "stdout" {
+			fmt.Fprintf(os.Stderr, "Error: --dry-run cannot be combined with --backend %s\n", *backend)
+			os.Exit(1)
+		}
+		*backend = "stdout"
+	}
+
+	overrides := map[string]any{}
+	if *format != "" {
+		overrides["format"] = *format
 	}
 
 	// Initialize dependencies
-	sender := outlookole.NewSender()
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	options.PostProcess = cfg.PostProcess
+	options.TemplatesDirs = cfg.TemplatesDirs
+
+	sender, err := newSender(*backend, cfg, overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Run the application
 	if err := runner.Run(sender, options); err != nil {
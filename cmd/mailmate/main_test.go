@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"mailmate/internal/config"
+)
+
+func TestResolveBackend(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   string
+		env    string
+		config string
+		want   string
+	}{
+		{name: "default", want: "outlook"},
+		{name: "config", config: "smtp", want: "smtp"},
+		{name: "environment over config", env: "eml", config: "smtp", want: "eml"},
+		{name: "flag over environment", flag: "stdout", env: "eml", config: "smtp", want: "stdout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAILMATE_BACKEND", tt.env)
+			if got := resolveBackend(tt.flag, &config.Config{Backend: tt.config}); got != tt.want {
+				t.Errorf("resolveBackend() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSenderFormat(t *testing.T) {
+	t.Setenv("MAILMATE_BACKEND", "")
+	overrides := map[string]any{"format": "json"}
+
+	if _, err := newSender("stdout", &config.Config{}, overrides); err != nil {
+		t.Errorf("newSender(stdout) error = %v", err)
+	}
+	if _, err := newSender("", &config.Config{Backend: "eml"}, overrides); err == nil {
+		t.Errorf("newSender(eml) error = nil, want an error for --format")
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/charmbracelet/huh v0.8.0
 	github.com/flosch/pongo2/v6 v6.0.0
 	github.com/go-ole/go-ole v1.3.0
+	github.com/yuin/goldmark v1.7.17
 	gopkg.in/yaml.v3 v3.0.1
 )
 
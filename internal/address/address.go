@@ -0,0 +1,103 @@
+package address
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// Parse parses a recipient field as written in template frontmatter and CLI flags.
+// Addresses may be separated by commas or semicolons, and may carry a display name
+// (e.g. `Jane Doe <jane@example.com>; "Doe, John" <john@example.com>`).
+// An empty field yields no addresses. The error names the offending entry.
+func Parse(field string) ([]mail.Address, error) {
+	var addrs []mail.Address
+	for _, entry := range split(field) {
+		a, err := mail.ParseAddress(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid email address %q: %s", entry, strings.TrimPrefix(err.Error(), "mail: "))
+		}
+		addrs = append(addrs, *a)
+	}
+	return addrs, nil
+}
+
+// split cuts a field on commas and semicolons that are not inside quotes or angle brackets.
+// Empty entries are dropped.
+func split(field string) []string {
+	var entries []string
+	var cur strings.Builder
+	inQuote, inAngle := false, false
+
+	flush := func() {
+		if entry := strings.TrimSpace(cur.String()); entry != "" {
+			entries = append(entries, entry)
+		}
+		cur.Reset()
+	}
+
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		switch {
+		case inQuote && c == '\\' && i+1 < len(field):
+			cur.WriteByte(c)
+			i++
+			c = field[i]
+		case c == '"':
+			inQuote = !inQuote
+		case !inQuote && c == '<':
+			inAngle = true
+		case !inQuote && c == '>':
+			inAngle = false
+		case !inQuote && !inAngle && (c == ',' || c == ';'):
+			flush()
+			continue
+		}
+		cur.WriteByte(c)
+	}
+	flush()
+	return entries
+}
+
+// Dedupe removes duplicate addresses, comparing them case-insensitively.
+// An address keeps its first occurrence in the order To, Cc, Bcc: someone in To is
+// dropped from Cc and Bcc, and someone in Cc is dropped from Bcc.
+func Dedupe(to, cc, bcc []mail.Address) ([]mail.Address, []mail.Address, []mail.Address) {
+	seen := make(map[string]bool)
+	filter := func(list []mail.Address) []mail.Address {
+		var out []mail.Address
+		for _, a := range list {
+			key := strings.ToLower(a.Address)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			out = append(out, a)
+		}
+		return out
+	}
+	return filter(to), filter(cc), filter(bcc)
+}
+
+// Format returns the address as a user would type it, without MIME encoding
+// (e.g. `Jane Doe <jane@example.com>`). Display names containing special
+// characters are quoted.
+func Format(a mail.Address) string {
+	if a.Name == "" {
+		return a.Address
+	}
+	name := a.Name
+	if strings.ContainsAny(name, `",;<>()[]:\@`) {
+		name = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+	}
+	return name + " <" + a.Address + ">"
+}
+
+// Join formats every address with Format and joins them with sep.
+func Join(list []mail.Address, sep string) string {
+	parts := make([]string, len(list))
+	for i, a := range list {
+		parts[i] = Format(a)
+	}
+	return strings.Join(parts, sep)
+}
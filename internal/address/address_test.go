@@ -0,0 +1,117 @@
+package address
+
+import (
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []mail.Address
+		wantErr string
+	}{
+		{
+			name:  "empty",
+			input: "  ",
+			want:  nil,
+		},
+		{
+			name:  "single address",
+			input: "jane@example.com",
+			want:  []mail.Address{{Address: "jane@example.com"}},
+		},
+		{
+			name:  "mixed separators and display names",
+			input: "Jane Doe <jane@example.com>; john@example.com, ",
+			want: []mail.Address{
+				{Name: "Jane Doe", Address: "jane@example.com"},
+				{Address: "john@example.com"},
+			},
+		},
+		{
+			name:  "separator inside quoted name",
+			input: `"Doe, John" <john@example.com>;marie@example.com`,
+			want: []mail.Address{
+				{Name: "Doe, John", Address: "john@example.com"},
+				{Address: "marie@example.com"},
+			},
+		},
+		{
+			name:    "invalid entry is named",
+			input:   "jane@example.com; not-an-address",
+			wantErr: `invalid email address "not-an-address"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Parse() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	to := []mail.Address{{Address: "jane@example.com"}, {Address: "JANE@example.com"}}
+	cc := []mail.Address{{Address: "jane@example.com"}, {Address: "boss@example.com"}}
+	bcc := []mail.Address{{Address: "Boss@Example.com"}, {Address: "archive@example.com"}}
+
+	gotTo, gotCc, gotBcc := Dedupe(to, cc, bcc)
+
+	if want := []mail.Address{{Address: "jane@example.com"}}; !reflect.DeepEqual(gotTo, want) {
+		t.Errorf("to = %v, want %v", gotTo, want)
+	}
+	if want := []mail.Address{{Address: "boss@example.com"}}; !reflect.DeepEqual(gotCc, want) {
+		t.Errorf("cc = %v, want %v", gotCc, want)
+	}
+	if want := []mail.Address{{Address: "archive@example.com"}}; !reflect.DeepEqual(gotBcc, want) {
+		t.Errorf("bcc = %v, want %v", gotBcc, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input mail.Address
+		want  string
+	}{
+		{
+			name:  "address only",
+			input: mail.Address{Address: "jane@example.com"},
+			want:  "jane@example.com",
+		},
+		{
+			name:  "display name",
+			input: mail.Address{Name: "Hélène Dupont", Address: "helene@example.com"},
+			want:  "Hélène Dupont <helene@example.com>",
+		},
+		{
+			name:  "display name with comma",
+			input: mail.Address{Name: "Doe, John", Address: "john@example.com"},
+			want:  `"Doe, John" <john@example.com>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.input); got != tt.want {
+				t.Errorf("Format() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
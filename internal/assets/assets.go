@@ -0,0 +1,48 @@
+// Package assets resolves the local files (images, stylesheets) referenced by the templates.
+package assets
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalPath returns the file path designated by the src or href of an HTML element,
+// or false if it designates a remote or embedded resource.
+// Relative paths are resolved from the first of dirs holding the file, or from the first
+// directory if none does.
+func LocalPath(ref string, dirs []string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "//") {
+		// Protocol-relative URLs designate remote resources
+		return "", false
+	}
+	// Checked first so that Windows drive letters are not mistaken for URL schemes.
+	if filepath.IsAbs(ref) {
+		return filepath.Clean(ref), true
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case u.Scheme == "file":
+		return filepath.FromSlash(u.Path), true
+	case u.Scheme != "" || u.Host != "" || u.Path == "":
+		return "", false
+	}
+
+	path := filepath.FromSlash(u.Path)
+	if filepath.IsAbs(path) || len(dirs) == 0 {
+		return path, true
+	}
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return filepath.Join(dirs[0], path), true
+}
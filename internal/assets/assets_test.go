@@ -0,0 +1,36 @@
+package assets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	template, root := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "logo.png"), []byte("png"), 0o600); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	dirs := []string{template, root}
+
+	tests := []struct {
+		ref    string
+		want   string
+		wantOK bool
+	}{
+		{ref: "logo.png", want: filepath.Join(root, "logo.png"), wantOK: true},
+		{ref: "missing.png", want: filepath.Join(template, "missing.png"), wantOK: true},
+		{ref: "file:///tmp/logo.png", want: filepath.FromSlash("/tmp/logo.png"), wantOK: true},
+		{ref: "https://example.com/logo.png"},
+		{ref: "//cdn.example.com/logo.png"},
+		{ref: "data:image/png;base64,AAAA"},
+		{ref: "cid:logo@mailmate"},
+		{ref: " "},
+	}
+	for _, tt := range tests {
+		got, ok := LocalPath(tt.ref, dirs)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("LocalPath(%q) = %q, %v, want %q, %v", tt.ref, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package calendar encodes meeting invitations as iCalendar (RFC 5545) requests.
+package calendar
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mailmate/internal/models"
+)
+
+// MethodRequest is the iTIP method of an invitation (RFC 5546).
+const MethodRequest = "REQUEST"
+
+// ContentType is the MIME type of an invitation.
+const ContentType = "text/calendar; method=REQUEST; charset=utf-8"
+
+// FileName is the name of the invitation when it is sent as an attachment.
+const FileName = "invitation.ics"
+
+const (
+	// utcLayout formats date-times in UTC.
+	utcLayout = "20060102T150405Z"
+	// dateLayout formats the dates of all-day events.
+	dateLayout = "20060102"
+	// maxLineLength is the maximum length of a content line, in octets, without the line break.
+	maxLineLength = 75
+)
+
+// NewUID returns a random event UID.
+func NewUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b) + "@mailmate"
+}
+
+// Encode returns the iCalendar request inviting the attendees of e.
+// Date-times are written in UTC, so that the invitation needs no VTIMEZONE component:
+// the time zone of the template is not kept, calendars show the times in their own zone.
+// now is the time stamp of the request.
+func Encode(e models.Event, now time.Time) ([]byte, error) {
+	if e.Organizer == nil {
+		return nil, errors.New("event: organizer is required")
+	}
+	if !e.End.After(e.Start) {
+		return nil, errors.New("event: end must be after start")
+	}
+	uid := e.UID
+	if uid == "" {
+		uid = NewUID()
+	}
+
+	var b strings.Builder
+	line := func(name, value string) {
+		writeLine(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("PRODID", "-//MailMate//MailMate//FR")
+	line("VERSION", "2.0")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", MethodRequest)
+	line("BEGIN", "VEVENT")
+	line("UID", escapeText(uid))
+	line("DTSTAMP", now.UTC().Format(utcLayout))
+	if e.AllDay {
+		line("DTSTART;VALUE=DATE", e.Start.Format(dateLayout))
+		line("DTEND;VALUE=DATE", e.End.Format(dateLayout))
+	} else {
+		line("DTSTART", e.Start.UTC().Format(utcLayout))
+		line("DTEND", e.End.UTC().Format(utcLayout))
+	}
+	line("SUMMARY", escapeText(e.Summary))
+	if e.Location != "" {
+		line("LOCATION", escapeText(e.Location))
+	}
+	if e.Description != "" {
+		line("DESCRIPTION", escapeText(e.Description))
+	}
+	line("ORGANIZER"+cn(*e.Organizer), "mailto:"+e.Organizer.Address)
+	for _, a := range e.Attendees {
+		line("ATTENDEE"+cn(a)+";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE", "mailto:"+a.Address)
+	}
+	for _, a := range e.OptionalAttendees {
+		line("ATTENDEE"+cn(a)+";ROLE=OPT-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE", "mailto:"+a.Address)
+	}
+	line("SEQUENCE", "0")
+	line("STATUS", "CONFIRMED")
+	line("TRANSP", "OPAQUE")
+	line("END", "VEVENT")
+	line("END", "VCALENDAR")
+	return []byte(b.String()), nil
+}
+
+// Attachment returns the invitation as an attachment, for backends that cannot send it as a body part.
+func Attachment(e models.Event, now time.Time) (models.Attachment, error) {
+	content, err := Encode(e, now)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	return models.Attachment{Content: content, Name: FileName, ContentType: ContentType}, nil
+}
+
+// cn returns the common name parameter of an address, or nothing if it has no display name.
+func cn(a mail.Address) string {
+	if a.Name == "" {
+		return ""
+	}
+	// Parameter values cannot contain double quotes
+	return `;CN="` + strings.ReplaceAll(a.Name, `"`, "'") + `"`
+}
+
+// escapeText escapes a TEXT value (RFC 5545 section 3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeLine writes a content line, folded at 75 octets without splitting UTF-8 characters.
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space
+		limit = maxLineLength - 1
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// WithInvitation returns the attachments of the draft, followed by the invitation of its event, if any.
+// It is used by the backends that cannot send the invitation as a body part (outlook, graph):
+// their drafts are regular emails with an .ics file, not meeting requests.
+// The organizer of the event defaults to the sender of the draft.
+func WithInvitation(draft models.DraftEmail, now time.Time) ([]models.Attachment, error) {
+	if draft.Event == nil {
+		return draft.Attachments, nil
+	}
+	event := *draft.Event
+	if event.Organizer == nil {
+		event.Organizer = draft.From
+	}
+	ics, err := Attachment(event, now)
+	if err != nil {
+		return nil, err
+	}
+	return append(draft.Attachments[:len(draft.Attachments):len(draft.Attachments)], ics), nil
+}
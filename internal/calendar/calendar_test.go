@@ -0,0 +1,144 @@
+package calendar
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"mailmate/internal/models"
+)
+
+func TestEncode(t *testing.T) {
+	paris, err := LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	event := models.Event{
+		UID:               "42@mailmate",
+		Summary:           "Point projet; lot 2, phase B",
+		Description:       "Ordre du jour :\n- planning",
+		Location:          "Salle Émeraude",
+		Start:             time.Date(2026, 10, 17, 14, 0, 0, 0, paris),
+		End:               time.Date(2026, 10, 17, 15, 30, 0, 0, paris),
+		Organizer:         &mail.Address{Name: "Jane Doe", Address: "jane@example.com"},
+		Attendees:         []mail.Address{{Address: "john@example.com"}},
+		OptionalAttendees: []mail.Address{{Name: `Marie "MD" Dupont`, Address: "marie@example.com"}},
+	}
+
+	got, err := Encode(event, time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//MailMate//MailMate//FR",
+		"VERSION:2.0",
+		"CALSCALE:GREGORIAN",
+		"METHOD:REQUEST",
+		"BEGIN:VEVENT",
+		"UID:42@mailmate",
+		"DTSTAMP:20261001T080000Z",
+		"DTSTART:20261017T120000Z",
+		"DTEND:20261017T133000Z",
+		`SUMMARY:Point projet\; lot 2\, phase B`,
+		"LOCATION:Salle Émeraude",
+		`DESCRIPTION:Ordre du jour :\n- planning`,
+		`ORGANIZER;CN="Jane Doe":mailto:jane@example.com`,
+		"ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:john@e",
+		" xample.com",
+		`ATTENDEE;CN="Marie 'MD' Dupont";ROLE=OPT-PARTICIPANT;PARTSTAT=NEEDS-ACTION;`,
+		" RSVP=TRUE:mailto:marie@example.com",
+		"SEQUENCE:0",
+		"STATUS:CONFIRMED",
+		"TRANSP:OPAQUE",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if string(got) != want {
+		t.Errorf("Encode() =\n%s\nwant\n%s", got, want)
+	}
+
+	event.Organizer = nil
+	if _, err := Encode(event, time.Now()); err == nil {
+		t.Errorf("Encode() error = nil, want an error without organizer")
+	}
+}
+
+func TestEncodeAllDay(t *testing.T) {
+	event := models.Event{
+		UID:       "1@mailmate",
+		Summary:   "Séminaire",
+		Start:     time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local),
+		End:       time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local),
+		AllDay:    true,
+		Organizer: &mail.Address{Address: "jane@example.com"},
+	}
+	got, err := Encode(event, time.Now())
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	for _, line := range []string{"DTSTART;VALUE=DATE:20261017\r\n", "DTEND;VALUE=DATE:20261019\r\n"} {
+		if !strings.Contains(string(got), line) {
+			t.Errorf("Encode() = %s, want line %q", got, line)
+		}
+	}
+	if want := "17-10-2026 → 18-10-2026 (all day)"; Describe(event) != want {
+		t.Errorf("Describe() = %q, want %q", Describe(event), want)
+	}
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	var b strings.Builder
+	writeLine(&b, "DESCRIPTION:"+strings.Repeat("é", 40))
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line %q is %d octets long", line, len(line))
+		}
+		if !strings.HasPrefix(line, "DESCRIPTION") && !strings.HasPrefix(line, " é") {
+			t.Errorf("line %q splits a character", line)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	paris, err := LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+
+	tests := []struct {
+		value      string
+		want       time.Time
+		wantAllDay bool
+		wantErr    bool
+	}{
+		{value: "17-10-2026 14:00", want: time.Date(2026, 10, 17, 14, 0, 0, 0, paris)},
+		{value: "17-10-2026 14h30", want: time.Date(2026, 10, 17, 14, 30, 0, 0, paris)},
+		{value: "2026-10-17T09:15", want: time.Date(2026, 10, 17, 9, 15, 0, 0, paris)},
+		{value: "2026-10-17T09:15:00Z", want: time.Date(2026, 10, 17, 9, 15, 0, 0, time.UTC)},
+		{value: " 17-10-2026 ", want: time.Date(2026, 10, 17, 0, 0, 0, 0, paris), wantAllDay: true},
+		{value: "17/10/2026 25:00", wantErr: true},
+		{value: "demain", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, allDay, err := ParseTime(tt.value, paris)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !got.Equal(tt.want) || allDay != tt.wantAllDay {
+				t.Errorf("ParseTime() = %v, %v, want %v, %v", got, allDay, tt.want, tt.wantAllDay)
+			}
+		})
+	}
+
+	if _, err := LoadLocation("Europe/Atlantis"); err == nil {
+		t.Errorf("LoadLocation() error = nil, want error for an unknown time zone")
+	}
+}
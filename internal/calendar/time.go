@@ -0,0 +1,94 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Embedded time zone database, for systems without one (Windows)
+	_ "time/tzdata"
+
+	"mailmate/internal/models"
+)
+
+// dateTimeLayouts are the accepted date-time formats. The first ones match the DD-MM-YYYY
+// format of the "date" type filter.
+var dateTimeLayouts = []string{
+	"02-01-2006 15:04",
+	"02-01-2006 15h04",
+	"02/01/2006 15:04",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+// dateLayouts are the accepted date formats, for all-day events.
+var dateLayouts = []string{
+	"02-01-2006",
+	"02/01/2006",
+	"2006-01-02",
+}
+
+// LoadLocation returns the named time zone (e.g. "Europe/Paris"), or the local time zone if name is empty.
+func LoadLocation(name string) (*time.Location, error) {
+	if strings.TrimSpace(name) == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(strings.TrimSpace(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// ParseTime parses an event date-time in the loc time zone. A date without a time
+// is the start of an all-day event, and an RFC 3339 value keeps its own offset.
+func ParseTime(value string, loc *time.Location) (t time.Time, allDay bool, err error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q (expected DD-MM-YYYY HH:MM or DD-MM-YYYY)", value)
+}
+
+// Describe returns a short human readable description of when the event takes place
+// (e.g. "17-10-2026 14:00 → 15:30 (1h30)").
+func Describe(e models.Event) string {
+	if e.AllDay {
+		last := e.End.AddDate(0, 0, -1)
+		if !last.After(e.Start) {
+			return e.Start.Format("02-01-2006") + " (all day)"
+		}
+		return e.Start.Format("02-01-2006") + " → " + last.Format("02-01-2006") + " (all day)"
+	}
+	end := e.End.In(e.Start.Location())
+	endLayout := "15:04"
+	if end.YearDay() != e.Start.YearDay() || end.Year() != e.Start.Year() {
+		endLayout = "02-01-2006 15:04"
+	}
+	return fmt.Sprintf("%s → %s (%s, %s)", e.Start.Format("02-01-2006 15:04"), end.Format(endLayout),
+		formatDuration(e.End.Sub(e.Start)), e.Start.Location())
+}
+
+// formatDuration formats a duration for display (e.g. "1h30").
+func formatDuration(d time.Duration) string {
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dmin", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	default:
+		return fmt.Sprintf("%dh%02d", h, m)
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"mailmate/internal/postprocess"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the MailMate configuration file.
+//
+// Example:
+//
+//	backend: smtp
+//	templates_dirs:
+//	  - ~/mailmate/templates
+//	  - //share/equipe/templates
+//	backends:
+//	  smtp:
+//	    host: smtp.example.com
+//	    from: "Jane <jane@example.com>"
+//	postprocess:
+//	  - name: signature
+//	    file: ~/signature.html
+type Config struct {
+	// Backend is the name of the default email backend.
+	Backend string `yaml:"backend"`
+	// Backends holds the options of each backend, keyed by backend name.
+	Backends map[string]yaml.Node `yaml:"backends"`
+	// TemplatesDirs is the templates search path: a template of an earlier directory
+	// overrides the template with the same relative path in the later ones.
+	TemplatesDirs []string `yaml:"templates_dirs"`
+	// PostProcess are the post-processing steps applied to every draft, after those of the template.
+	PostProcess postprocess.Steps `yaml:"postprocess"`
+}
+
+// DefaultPath returns the configuration file path.
+// It first checks the MAILMATE_CONFIG environment variable.
+// If not set, it defaults to "mailmate/config.yaml" in the user configuration directory.
+func DefaultPath() string {
+	if path := os.Getenv("MAILMATE_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mailmate", "config.yaml")
+}
+
+// Load reads the configuration file at path.
+// If the file does not exist and required is false, an empty configuration is returned.
+func Load(path string, required bool) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %q: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// BackendDecoder returns a function decoding the options of the named backend.
+// Unknown options are reported, so that typos do not go unnoticed.
+// The returned function is a no-op if the configuration has no section for that backend.
+func (c *Config) BackendDecoder(name string) func(v any) error {
+	node, ok := c.Backends[name]
+	if !ok {
+		return func(any) error { return nil }
+	}
+	return func(v any) error {
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			return fmt.Errorf("encoding options for backend %q: %w", name, err)
+		}
+		if err := decodeStrict(data, v); err != nil {
+			return fmt.Errorf("invalid options for backend %q: %w", name, err)
+		}
+		return nil
+	}
+}
+
+// decodeStrict decodes the YAML document data into v, rejecting unknown fields.
+// An empty document leaves v untouched.
+func decodeStrict(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// WithOverrides returns a decode function that applies decode, then sets the given
+// options on top of it. It is used to let CLI flags take precedence over the config file.
+func WithOverrides(decode func(v any) error, overrides map[string]any) func(v any) error {
+	return func(v any) error {
+		if err := decode(v); err != nil {
+			return err
+		}
+		if len(overrides) == 0 {
+			return nil
+		}
+		data, err := yaml.Marshal(overrides)
+		if err != nil {
+			return fmt.Errorf("encoding option overrides: %w", err)
+		}
+		if err := decodeStrict(data, v); err != nil {
+			return fmt.Errorf("applying option overrides: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := `backend: smtp
+backends:
+  smtp:
+    host: smtp.example.com
+    port: 465
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path, true)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Backend != "smtp" {
+		t.Errorf("Backend = %q, want smtp", cfg.Backend)
+	}
+
+	var opts struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	if err := cfg.BackendDecoder("smtp")(&opts); err != nil {
+		t.Fatalf("BackendDecoder() error = %v", err)
+	}
+	if opts.Host != "smtp.example.com" || opts.Port != 465 {
+		t.Errorf("decoded options = %+v", opts)
+	}
+
+	// A backend without a section leaves the options untouched.
+	opts.Host = "unchanged"
+	if err := cfg.BackendDecoder("eml")(&opts); err != nil {
+		t.Fatalf("BackendDecoder() error = %v", err)
+	}
+	if opts.Host != "unchanged" {
+		t.Errorf("Host = %q, want unchanged", opts.Host)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+
+	cfg, err := Load(path, false)
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil for optional file", err)
+	}
+	if cfg.Backend != "" {
+		t.Errorf("Backend = %q, want empty", cfg.Backend)
+	}
+
+	if _, err := Load(path, true); err == nil {
+		t.Errorf("Load() error = nil, want error for required file")
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal([]byte("backends:\n  stdout:\n    format: text\n    body: file\n"), cfg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	var opts struct {
+		Format string `yaml:"format"`
+		Body   string `yaml:"body"`
+	}
+	decode := WithOverrides(cfg.BackendDecoder("stdout"), map[string]any{"format": "json"})
+	if err := decode(&opts); err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	if opts.Format != "json" || opts.Body != "file" {
+		t.Errorf("options = %+v, want format overridden and body kept", opts)
+	}
+}
+
+func TestBackendDecoderUnknownOptions(t *testing.T) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal([]byte("backends:\n  smtp:\n    hots: smtp.example.com\n  eml:\n"), cfg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	var opts struct {
+		Host string `yaml:"host"`
+	}
+	if err := cfg.BackendDecoder("smtp")(&opts); err == nil {
+		t.Errorf("BackendDecoder() error = nil, want an error for an unknown option")
+	}
+	if err := cfg.BackendDecoder("eml")(&opts); err != nil {
+		t.Errorf("BackendDecoder() error = %v for an empty section", err)
+	}
+	if err := WithOverrides(cfg.BackendDecoder("eml"), map[string]any{"format": "json"})(&opts); err == nil {
+		t.Errorf("WithOverrides() error = nil, want an error for an unknown option")
+	}
+}
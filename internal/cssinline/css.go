@@ -0,0 +1,185 @@
+package cssinline
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commentRegex matches CSS comments.
+var commentRegex = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// importantRegex matches the !important flag at the end of a declaration value.
+var importantRegex = regexp.MustCompile(`(?i)\s*!\s*important\s*$`)
+
+// declaration is a CSS property assignment.
+type declaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+// rule is a style rule whose selector can be resolved against the document.
+type rule struct {
+	selector     selector
+	specificity  specificity
+	declarations []declaration
+}
+
+// stylesheet is a parsed style sheet.
+type stylesheet struct {
+	// rules are the rules to inline.
+	rules []rule
+	// kept is the CSS that cannot be inlined (media queries, at-rules, pseudo-classes...)
+	// and must stay in a <style> block.
+	kept []string
+}
+
+// parseStylesheet parses CSS into inlinable rules and kept CSS.
+func parseStylesheet(css string) stylesheet {
+	var sheet stylesheet
+	css = commentRegex.ReplaceAllString(css, "")
+
+	for pos := 0; pos < len(css); {
+		rest := strings.TrimLeft(css[pos:], " \t\r\n\f")
+		pos = len(css) - len(rest)
+		if rest == "" {
+			break
+		}
+		// Stray HTML comment delimiters are allowed around style content
+		if strings.HasPrefix(rest, "<!--") {
+			pos += len("<!--")
+			continue
+		}
+		if strings.HasPrefix(rest, "-->") {
+			pos += len("-->")
+			continue
+		}
+
+		open := indexOutsideQuotes(rest, '{')
+		if rest[0] == '@' {
+			// Statement at-rules (@import, @charset) end with a semicolon
+			if semi := indexOutsideQuotes(rest, ';'); semi != -1 && (open == -1 || semi < open) {
+				sheet.kept = append(sheet.kept, strings.TrimSpace(rest[:semi+1]))
+				pos += semi + 1
+				continue
+			}
+		}
+		if open == -1 {
+			break
+		}
+		end := matchingBrace(rest, open)
+		if end == -1 {
+			end = len(rest) - 1
+		}
+		prelude := strings.TrimSpace(rest[:open])
+		body := rest[open+1 : end]
+		pos += end + 1
+
+		// Block at-rules (@media, @font-face...) are kept as is
+		if strings.HasPrefix(prelude, "@") {
+			sheet.kept = append(sheet.kept, prelude+" {"+body+"}")
+			continue
+		}
+
+		declarations := parseDeclarations(body)
+		var unsupported []string
+		for _, s := range splitOutsideQuotes(prelude, ',') {
+			sel, err := parseSelector(s)
+			if err != nil {
+				unsupported = append(unsupported, strings.TrimSpace(s))
+				continue
+			}
+			sheet.rules = append(sheet.rules, rule{selector: sel, specificity: sel.specificity(), declarations: declarations})
+		}
+		if len(unsupported) > 0 {
+			sheet.kept = append(sheet.kept, strings.Join(unsupported, ", ")+" {"+body+"}")
+		}
+	}
+	return sheet
+}
+
+// parseDeclarations parses the declarations of a rule or of a style attribute.
+func parseDeclarations(s string) []declaration {
+	var declarations []declaration
+	for _, d := range splitOutsideQuotes(s, ';') {
+		property, value, ok := strings.Cut(d, ":")
+		if !ok {
+			continue
+		}
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.TrimSpace(value)
+		important := importantRegex.MatchString(value)
+		if important {
+			value = importantRegex.ReplaceAllString(value, "")
+		}
+		if property == "" || value == "" {
+			continue
+		}
+		declarations = append(declarations, declaration{property: property, value: value, important: important})
+	}
+	return declarations
+}
+
+// indexOutsideQuotes returns the index of the first c of s outside quoted strings and parentheses, or -1.
+func indexOutsideQuotes(s string, c byte) int {
+	quote := byte(0)
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '(':
+			depth++
+		case s[i] == ')' && depth > 0:
+			depth--
+		case s[i] == c && depth == 0:
+			return i
+		}
+	}
+	return -1
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside quoted strings and parentheses.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	for {
+		i := indexOutsideQuotes(s, sep)
+		if i == -1 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open, or -1.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	quote := byte(0)
+	for i := open; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,153 @@
+package cssinline
+
+import (
+	"html"
+	"strings"
+
+	"mailmate/internal/htmltoken"
+)
+
+// voidElements have no content and no end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// impliedEnd lists, for an element, the open elements its start tag implicitly closes.
+var impliedEnd = map[string]map[string]bool{
+	"li":     {"li": true},
+	"dt":     {"dt": true, "dd": true},
+	"dd":     {"dt": true, "dd": true},
+	"tr":     {"tr": true, "td": true, "th": true},
+	"td":     {"td": true, "th": true},
+	"th":     {"td": true, "th": true},
+	"thead":  {"thead": true, "tbody": true, "tfoot": true, "tr": true, "td": true, "th": true},
+	"tbody":  {"thead": true, "tbody": true, "tfoot": true, "tr": true, "td": true, "th": true},
+	"tfoot":  {"thead": true, "tbody": true, "tfoot": true, "tr": true, "td": true, "th": true},
+	"option": {"option": true},
+}
+
+// closesParagraph lists the elements whose start tag implicitly closes an open <p>.
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "div": true, "dl": true,
+	"fieldset": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "ul": true,
+}
+
+// element is an HTML element of the parsed document.
+type element struct {
+	name  string
+	attrs map[string]string
+	// start and end are the byte offsets of the start tag in the source.
+	start, end int
+	// closeEnd is the byte offset following the end tag, for raw text elements.
+	closeEnd int
+	// text is the content of raw text elements.
+	text string
+
+	parent   *element
+	children []*element
+}
+
+// attr returns the value of an attribute.
+func (e *element) attr(name string) string {
+	return e.attrs[name]
+}
+
+// index returns the position of the element among the element children of its parent.
+func (e *element) index() int {
+	if e.parent == nil {
+		return 0
+	}
+	for i, c := range e.parent.children {
+		if c == e {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseHTML parses the document into a tree of elements and returns them in document order.
+// Top-level elements are children of a synthetic root element.
+// It is a lenient parser: end tags without a matching open element are ignored,
+// and unclosed elements are closed at the end of the document.
+func parseHTML(src string) []*element {
+	root := &element{name: "#root"}
+	stack := []*element{root}
+	var all []*element
+	// raw is the raw text element whose content and end tag are read next.
+	var raw *element
+
+	tokens := htmltoken.New(src)
+	for {
+		tok, ok := tokens.Next()
+		if !ok {
+			break
+		}
+
+		switch tok.Kind {
+		case htmltoken.Text:
+			if raw != nil && tok.Start == raw.end {
+				raw.text = tok.Data
+			}
+			continue
+		case htmltoken.EndTag:
+			if raw != nil {
+				raw.closeEnd = tok.End
+				raw = nil
+				continue
+			}
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == tok.Name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		name := tok.Name
+		top := stack[len(stack)-1]
+		if top.name == "p" && closesParagraph[name] {
+			stack = stack[:len(stack)-1]
+		}
+		for closes := impliedEnd[name]; len(stack) > 1 && closes[stack[len(stack)-1].name]; {
+			stack = stack[:len(stack)-1]
+		}
+
+		parent := stack[len(stack)-1]
+		e := &element{name: name, attrs: tok.Attrs, start: tok.Start, end: tok.End, parent: parent}
+		parent.children = append(parent.children, e)
+		all = append(all, e)
+
+		switch {
+		case htmltoken.IsRawText(name):
+			// Unclosed raw text elements run up to the end of the document
+			e.closeEnd = len(src)
+			raw = e
+		case voidElements[name], tok.SelfClosing:
+		default:
+			stack = append(stack, e)
+		}
+	}
+	return all
+}
+
+// setStyle returns the start tag src[e.start:e.end] with its style attribute set to style.
+func setStyle(src string, e *element, style string) string {
+	tag := src[e.start:e.end]
+	value := `style="` + html.EscapeString(style) + `"`
+
+	if start, end, ok := htmltoken.AttrIndex(tag, "style"); ok {
+		return tag[:start] + value + tag[end:]
+	}
+
+	// No style attribute: insert it before the end of the tag
+	insertAt := len(tag) - 1
+	if strings.HasSuffix(tag, "/>") {
+		insertAt--
+	}
+	prefix := strings.TrimRight(tag[:insertAt], " \t\r\n")
+	return prefix + " " + value + tag[insertAt:]
+}
@@ -0,0 +1,196 @@
+// Package cssinline moves the CSS rules of an HTML document into style attributes,
+// for mail clients that ignore <style> blocks such as Outlook.
+package cssinline
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"mailmate/internal/assets"
+)
+
+// skippedElements never receive inlined styles.
+var skippedElements = map[string]bool{
+	"html": true, "head": true, "title": true, "meta": true, "base": true,
+	"link": true, "style": true, "script": true,
+}
+
+// source is a <style> or <link rel="stylesheet"> element of the document.
+type source struct {
+	element *element
+	sheet   stylesheet
+}
+
+// candidate is a declaration applying to an element, with what is needed to resolve the cascade.
+type candidate struct {
+	declaration
+	inline      bool
+	specificity specificity
+	order       int
+}
+
+// less reports whether c has a lower priority than o in the cascade.
+func (c candidate) less(o candidate) bool {
+	switch {
+	case c.important != o.important:
+		return !c.important
+	case c.inline != o.inline:
+		return !c.inline
+	case c.specificity != o.specificity:
+		return c.specificity.less(o.specificity)
+	default:
+		return c.order < o.order
+	}
+}
+
+// edit replaces the source bytes [start, end) with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// Inline applies the rules of the <style> blocks and of the linked local stylesheets
+// to the style attribute of the elements they match.
+//
+// Rules are applied following the cascade: !important declarations first, then the
+// existing style attributes, then the selector specificity and the source order.
+// Whatever cannot be inlined (media queries, @font-face, pseudo-classes such as :hover)
+// stays in a <style> block; style blocks and links left empty are removed.
+// Relative stylesheet paths are resolved from the first of baseDirs holding the file
+// (see assets.LocalPath). Remote stylesheets are left untouched, as are style blocks
+// whose media attribute does not target screens.
+func Inline(body string, baseDirs ...string) (string, error) {
+	elements := parseHTML(body)
+
+	var sources []source
+	for _, e := range elements {
+		switch e.name {
+		case "style":
+			if !screenMedia(e.attr("media")) {
+				continue
+			}
+			sources = append(sources, source{element: e, sheet: parseStylesheet(e.text)})
+		case "link":
+			if !contains(strings.Fields(strings.ToLower(e.attr("rel"))), "stylesheet") || !screenMedia(e.attr("media")) {
+				continue
+			}
+			path, ok := assets.LocalPath(e.attr("href"), baseDirs)
+			if !ok {
+				continue
+			}
+			css, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("reading stylesheet %q: %w", e.attr("href"), err)
+			}
+			sources = append(sources, source{element: e, sheet: parseStylesheet(string(css))})
+		}
+	}
+	if len(sources) == 0 {
+		return body, nil
+	}
+
+	var edits []edit
+	for _, e := range elements {
+		if skippedElements[e.name] || inHead(e) {
+			continue
+		}
+		if style, ok := computeStyle(e, sources); ok {
+			edits = append(edits, edit{start: e.start, end: e.end, text: setStyle(body, e, style)})
+		}
+	}
+
+	for _, s := range sources {
+		e := s.element
+		kept := ""
+		if len(s.sheet.kept) > 0 {
+			kept = "\n" + strings.Join(s.sheet.kept, "\n") + "\n"
+		}
+		switch {
+		case e.name == "link" && kept != "":
+			edits = append(edits, edit{start: e.start, end: e.end, text: "<style>" + kept + "</style>"})
+		case e.name == "link":
+			edits = append(edits, edit{start: e.start, end: e.end})
+		case kept != "":
+			edits = append(edits, edit{start: e.end, end: e.end + len(e.text), text: kept})
+		default:
+			edits = append(edits, edit{start: e.start, end: e.closeEnd})
+		}
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out strings.Builder
+	last := 0
+	for _, ed := range edits {
+		out.WriteString(body[last:ed.start])
+		out.WriteString(ed.text)
+		last = ed.end
+	}
+	out.WriteString(body[last:])
+	return out.String(), nil
+}
+
+// computeStyle returns the style attribute of e once the matching rules are applied,
+// or false if no rule matches.
+func computeStyle(e *element, sources []source) (string, bool) {
+	winners := make(map[string]candidate)
+	order := 0
+	consider := func(c candidate) {
+		if w, ok := winners[c.property]; !ok || w.less(c) {
+			winners[c.property] = c
+		}
+	}
+
+	matched := false
+	for _, s := range sources {
+		for _, r := range s.sheet.rules {
+			if !r.selector.matches(e) {
+				order += len(r.declarations)
+				continue
+			}
+			matched = true
+			for _, d := range r.declarations {
+				order++
+				consider(candidate{declaration: d, specificity: r.specificity, order: order})
+			}
+		}
+	}
+	if !matched {
+		return "", false
+	}
+	for _, d := range parseDeclarations(e.attr("style")) {
+		order++
+		consider(candidate{declaration: d, inline: true, order: order})
+	}
+
+	// Writing the declarations from the lowest to the highest priority keeps the cascade
+	// between shorthand and longhand properties (e.g. margin and margin-top).
+	list := make([]candidate, 0, len(winners))
+	for _, c := range winners {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].less(list[j]) })
+
+	parts := make([]string, len(list))
+	for i, c := range list {
+		parts[i] = c.property + ": " + c.value
+	}
+	return strings.Join(parts, "; "), true
+}
+
+// inHead reports whether e is inside the <head> element.
+func inHead(e *element) bool {
+	for p := e.parent; p != nil; p = p.parent {
+		if p.name == "head" {
+			return true
+		}
+	}
+	return false
+}
+
+// screenMedia reports whether a media attribute applies to screens.
+func screenMedia(media string) bool {
+	media = strings.ToLower(strings.TrimSpace(media))
+	return media == "" || media == "all" || media == "screen"
+}
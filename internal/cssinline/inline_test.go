@@ -0,0 +1,110 @@
+package cssinline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInline(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mail.css"), []byte(".footer { color: #777; font-size: 12px }"), 0o600); err != nil {
+		t.Fatalf("Failed to write stylesheet: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		html    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "rules moved to style attributes",
+			html: `<html><head><style>body { font-family: sans-serif; } .highlight { color: #0056b3; font-weight: bold; }</style></head>` +
+				`<body><p>Date : <span class="highlight">lundi</span></p></body></html>`,
+			want: `<html><head></head>` +
+				`<body style="font-family: sans-serif"><p>Date : <span class="highlight" style="color: #0056b3; font-weight: bold">lundi</span></p></body></html>`,
+		},
+		{
+			name: "specificity, source order and existing style attribute",
+			html: `<style>#intro { color: red } p.note { color: blue; margin: 0 } p { color: green; margin-top: 10px } .x { padding: 1px }</style>` +
+				`<p id="intro" class="note">a</p><p class="note" style="color: black">b</p><p class="x" style='padding: 2px'>c</p>`,
+			want: `<p id="intro" class="note" style="margin-top: 10px; margin: 0; color: red">a</p>` +
+				`<p class="note" style="margin-top: 10px; margin: 0; color: black">b</p>` +
+				`<p class="x" style="color: green; margin-top: 10px; padding: 2px">c</p>`,
+		},
+		{
+			name: "important declarations win over style attributes",
+			html: `<style>td { color: red !important; }</style><table><tr><td style="color: blue">a</td></tr></table>`,
+			want: `<table><tr><td style="color: red">a</td></tr></table>`,
+		},
+		{
+			name: "combinators and attributes",
+			html: `<style>ul > li { margin: 0 } li + li { border-top: 1px solid } div a[href^="https"] { color: green } li:first-child { font-weight: bold }</style>` +
+				`<div><ul><li>a<li>b</ul><p><a href="https://example.com">x</a><a href="/local">y</a></p></div>`,
+			want: `<div><ul><li style="margin: 0; font-weight: bold">a<li style="margin: 0; border-top: 1px solid">b</ul>` +
+				`<p><a href="https://example.com" style="color: green">x</a><a href="/local">y</a></p></div>`,
+		},
+		{
+			name: "media queries and pseudo-classes kept",
+			html: "<style>\n/* titre */\na { color: blue } a:hover { color: red }\n@media (max-width: 600px) { .col { width: 100% !important; } }\n</style><a href=\"#\">x</a>",
+			want: "<style>\na:hover { color: red }\n@media (max-width: 600px) { .col { width: 100% !important; } }\n</style><a href=\"#\" style=\"color: blue\">x</a>",
+		},
+		{
+			name: "linked local stylesheet",
+			html: `<head><link rel="stylesheet" href="mail.css"><link rel="stylesheet" href="https://example.com/a.css"></head><div class="footer">Merci</div>`,
+			want: `<head><link rel="stylesheet" href="https://example.com/a.css"></head><div class="footer" style="color: #777; font-size: 12px">Merci</div>`,
+		},
+		{
+			name: "quotes escaped in the attribute",
+			html: `<style>p { font-family: "Segoe UI", sans-serif }</style><p/>`,
+			want: `<p style="font-family: &#34;Segoe UI&#34;, sans-serif"/>`,
+		},
+		{
+			name:    "missing stylesheet",
+			html:    `<link rel="stylesheet" href="missing.css">`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Stylesheets not found next to the template are looked up in the templates directory
+			got, err := Inline(tt.html, filepath.Join(dir, "relances"), dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Inline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Inline() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     specificity
+		wantErr  bool
+	}{
+		{selector: "p", want: specificity{0, 0, 1}},
+		{selector: "div.header > h2", want: specificity{0, 1, 2}},
+		{selector: "#main .note a[href]", want: specificity{1, 2, 1}},
+		{selector: "*", want: specificity{0, 0, 0}},
+		{selector: "a:hover", wantErr: true},
+		{selector: "p::first-line", wantErr: true},
+		{selector: "> p", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := parseSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && sel.specificity() != tt.want {
+				t.Errorf("specificity() = %v, want %v", sel.specificity(), tt.want)
+			}
+		})
+	}
+}
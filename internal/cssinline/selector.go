@@ -0,0 +1,309 @@
+package cssinline
+
+import (
+	"errors"
+	"strings"
+)
+
+// errUnsupported is returned for selectors that cannot be resolved statically
+// (e.g. :hover, ::before), whose rules are kept in a <style> block.
+var errUnsupported = errors.New("unsupported selector")
+
+// attrSelector is an attribute condition such as [type="text"].
+type attrSelector struct {
+	name  string
+	op    string
+	value string
+}
+
+// compound is a sequence of simple selectors applying to one element, such as p.note#intro.
+type compound struct {
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrSelector
+	firstChild bool
+	lastChild  bool
+}
+
+// selector is a complex selector: compounds joined by combinators.
+type selector struct {
+	compounds []compound
+	// combinators[i] joins compounds[i] and compounds[i+1]: ' ', '>', '+' or '~'.
+	combinators []byte
+}
+
+// specificity is the (ids, classes, types) specificity of a selector.
+type specificity [3]int
+
+// less reports whether s has a lower specificity than o.
+func (s specificity) less(o specificity) bool {
+	for i := range s {
+		if s[i] != o[i] {
+			return s[i] < o[i]
+		}
+	}
+	return false
+}
+
+// parseSelector parses a single complex selector (without commas).
+func parseSelector(s string) (selector, error) {
+	var sel selector
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return sel, errUnsupported
+	}
+
+	var cur compound
+	empty := true
+	pendingCombinator := byte(0)
+
+	flush := func() error {
+		if empty {
+			return errUnsupported
+		}
+		if len(sel.compounds) > 0 {
+			sel.combinators = append(sel.combinators, pendingCombinator)
+		}
+		sel.compounds = append(sel.compounds, cur)
+		cur, empty, pendingCombinator = compound{}, true, 0
+		return nil
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '>' || c == '+' || c == '~':
+			if !empty {
+				if err := flush(); err != nil {
+					return sel, err
+				}
+				pendingCombinator = ' '
+			}
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				if len(sel.compounds) == 0 || (pendingCombinator != ' ' && pendingCombinator != 0) {
+					return sel, errUnsupported
+				}
+				pendingCombinator = c
+			}
+			i++
+		case c == '*':
+			empty = false
+			i++
+		case c == '.' || c == '#':
+			name, n := readIdent(s[i+1:])
+			if name == "" {
+				return sel, errUnsupported
+			}
+			if c == '.' {
+				cur.classes = append(cur.classes, name)
+			} else {
+				cur.id = name
+			}
+			empty = false
+			i += 1 + n
+		case c == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end == -1 {
+				return sel, errUnsupported
+			}
+			a, err := parseAttrSelector(s[i+1 : i+end])
+			if err != nil {
+				return sel, err
+			}
+			cur.attrs = append(cur.attrs, a)
+			empty = false
+			i += end + 1
+		case c == ':':
+			name, n := readIdent(s[i+1:])
+			switch strings.ToLower(name) {
+			case "first-child":
+				cur.firstChild = true
+			case "last-child":
+				cur.lastChild = true
+			default:
+				return sel, errUnsupported
+			}
+			empty = false
+			i += 1 + n
+		default:
+			name, n := readIdent(s[i:])
+			if name == "" || !empty {
+				return sel, errUnsupported
+			}
+			cur.tag = strings.ToLower(name)
+			empty = false
+			i += n
+		}
+	}
+	if err := flush(); err != nil {
+		return sel, err
+	}
+	return sel, nil
+}
+
+// readIdent reads a CSS identifier at the start of s and returns it with its length.
+func readIdent(s string) (string, int) {
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		if c == '-' || c == '_' || c >= 0x80 || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			n++
+			continue
+		}
+		break
+	}
+	return s[:n], n
+}
+
+// parseAttrSelector parses the content of an attribute selector, without the brackets.
+func parseAttrSelector(s string) (attrSelector, error) {
+	i := strings.IndexAny(s, "=~^$*|")
+	if i == -1 {
+		name := strings.TrimSpace(s)
+		if name == "" {
+			return attrSelector{}, errUnsupported
+		}
+		return attrSelector{name: strings.ToLower(name)}, nil
+	}
+
+	a := attrSelector{name: strings.ToLower(strings.TrimSpace(s[:i]))}
+	rest := s[i:]
+	switch {
+	case strings.HasPrefix(rest, "="):
+		a.op, rest = "=", rest[1:]
+	case len(rest) > 1 && rest[1] == '=':
+		a.op, rest = rest[:2], rest[2:]
+	default:
+		return attrSelector{}, errUnsupported
+	}
+	a.value = strings.Trim(strings.TrimSpace(rest), `"'`)
+	if a.name == "" {
+		return attrSelector{}, errUnsupported
+	}
+	return a, nil
+}
+
+// specificity computes the specificity of the selector.
+func (sel selector) specificity() specificity {
+	var sp specificity
+	for _, c := range sel.compounds {
+		if c.id != "" {
+			sp[0]++
+		}
+		sp[1] += len(c.classes) + len(c.attrs)
+		if c.firstChild {
+			sp[1]++
+		}
+		if c.lastChild {
+			sp[1]++
+		}
+		if c.tag != "" {
+			sp[2]++
+		}
+	}
+	return sp
+}
+
+// matches reports whether the element matches the selector.
+func (sel selector) matches(e *element) bool {
+	return sel.matchesAt(e, len(sel.compounds)-1)
+}
+
+// matchesAt reports whether e matches the selector made of the compounds up to index i.
+func (sel selector) matchesAt(e *element, i int) bool {
+	if !sel.compounds[i].matches(e) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	switch sel.combinators[i-1] {
+	case '>':
+		return e.parent != nil && e.parent.parent != nil && sel.matchesAt(e.parent, i-1)
+	case '+':
+		idx := e.index()
+		return idx > 0 && sel.matchesAt(e.parent.children[idx-1], i-1)
+	case '~':
+		for j := e.index() - 1; j >= 0; j-- {
+			if sel.matchesAt(e.parent.children[j], i-1) {
+				return true
+			}
+		}
+		return false
+	default:
+		for p := e.parent; p != nil && p.parent != nil; p = p.parent {
+			if sel.matchesAt(p, i-1) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// matches reports whether the element matches every simple selector of the compound.
+func (c compound) matches(e *element) bool {
+	if c.tag != "" && c.tag != e.name {
+		return false
+	}
+	if c.id != "" && e.attr("id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(e.attr("class"))
+		for _, want := range c.classes {
+			if !contains(classes, want) {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		if !a.matches(e) {
+			return false
+		}
+	}
+	if c.firstChild && e.index() != 0 {
+		return false
+	}
+	if c.lastChild && (e.parent == nil || e.index() != len(e.parent.children)-1) {
+		return false
+	}
+	return true
+}
+
+// matches reports whether the element satisfies the attribute condition.
+func (a attrSelector) matches(e *element) bool {
+	v, ok := e.attrs[a.name]
+	if !ok {
+		return false
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return v == a.value
+	case "~=":
+		return contains(strings.Fields(v), a.value)
+	case "|=":
+		return v == a.value || strings.HasPrefix(v, a.value+"-")
+	case "^=":
+		return a.value != "" && strings.HasPrefix(v, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(v, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(v, a.value)
+	default:
+		return false
+	}
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
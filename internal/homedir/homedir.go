@@ -0,0 +1,22 @@
+// Package homedir expands the "~" shorthand of the paths found in the configuration and templates.
+package homedir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Expand replaces a leading "~" designating the home directory: "~" alone, or followed by
+// a path separator ("~/", or "~\" on Windows). Other paths, such as "~bob/templates", are
+// returned unchanged.
+func Expand(path string) (string, error) {
+	if path != "~" && (len(path) < 2 || path[0] != '~' || !os.IsPathSeparator(path[1])) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolving home directory: %w", err)
+	}
+	return filepath.Join(home, path[1:]), nil
+}
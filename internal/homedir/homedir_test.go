@@ -0,0 +1,34 @@
+package homedir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "~", want: home},
+		{path: "~/templates", want: filepath.Join(home, "templates")},
+		{path: "~bob/templates", want: "~bob/templates"},
+		{path: "templates/~", want: "templates/~"},
+		{path: "", want: ""},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.path)
+		if err != nil {
+			t.Fatalf("Expand(%q) error = %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package htmltoken splits HTML documents into text and tags.
+// It is a lenient tokenizer shared by the packages reading email bodies
+// (plain-text conversion, CSS inlining); it does not build a document tree.
+package htmltoken
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// attrRegex matches one attribute of a start tag: name, and value in group 2, 3 or 4.
+var attrRegex = regexp.MustCompile(`([^\s"'>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+
+// rawTextElements hold text that must not be parsed as HTML.
+var rawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// Kind is the kind of a token.
+type Kind int
+
+const (
+	// Text is a text node, or the content of a raw text element (script, style, textarea, title).
+	Text Kind = iota
+	// StartTag is a start tag, such as <p class="note"> or <br/>.
+	StartTag
+	// EndTag is an end tag, such as </p>.
+	EndTag
+)
+
+// Token is a text node or a tag of the document.
+type Token struct {
+	Kind Kind
+	// Data is the source text of Text tokens, not unescaped.
+	Data string
+	// Name is the lowercase tag name of StartTag and EndTag tokens.
+	Name string
+	// Attrs are the attributes of StartTag tokens, keyed by lowercase name, with unescaped values.
+	Attrs map[string]string
+	// SelfClosing records whether a start tag ends with "/>".
+	SelfClosing bool
+	// Start and End are the byte offsets of the token in the source.
+	Start, End int
+}
+
+// Tokenizer reads the tokens of a document.
+// Comments, doctypes and processing instructions are skipped. A '<' that does not
+// start a tag, because the document ends before its '>', is returned as text.
+type Tokenizer struct {
+	src string
+	pos int
+	// raw is the name of the raw text element whose content is read next.
+	raw string
+}
+
+// New returns a tokenizer reading src.
+func New(src string) *Tokenizer {
+	return &Tokenizer{src: src}
+}
+
+// Next returns the next token, or false at the end of the document.
+func (t *Tokenizer) Next() (Token, bool) {
+	for t.pos < len(t.src) {
+		start := t.pos
+		if t.raw != "" {
+			// The content of a raw text element runs up to its end tag
+			end := strings.Index(strings.ToLower(t.src[start:]), "</"+t.raw)
+			t.raw = ""
+			if end == -1 {
+				end = len(t.src) - start
+			}
+			if end == 0 {
+				continue
+			}
+			t.pos = start + end
+			return Token{Kind: Text, Data: t.src[start:t.pos], Start: start, End: t.pos}, true
+		}
+
+		if t.src[start] != '<' {
+			end := strings.IndexByte(t.src[start:], '<')
+			if end == -1 {
+				end = len(t.src) - start
+			}
+			t.pos = start + end
+			return Token{Kind: Text, Data: t.src[start:t.pos], Start: start, End: t.pos}, true
+		}
+
+		if strings.HasPrefix(t.src[start:], "<!--") {
+			end := strings.Index(t.src[start+4:], "-->")
+			if end == -1 {
+				t.pos = len(t.src)
+			} else {
+				t.pos = start + 4 + end + 3
+			}
+			continue
+		}
+
+		end := tagEnd(t.src, start)
+		if end == -1 {
+			// Not a tag: the rest of the document is text
+			t.pos = len(t.src)
+			return Token{Kind: Text, Data: t.src[start:], Start: start, End: t.pos}, true
+		}
+		t.pos = end + 1
+
+		raw := t.src[start+1 : end]
+		if raw == "" || raw[0] == '!' || raw[0] == '?' {
+			continue
+		}
+		closing := raw[0] == '/'
+		raw = strings.TrimPrefix(raw, "/")
+		name := raw
+		if i := strings.IndexAny(raw, " \t\r\n/"); i != -1 {
+			name = raw[:i]
+		}
+		name = strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+
+		if closing {
+			return Token{Kind: EndTag, Name: name, Start: start, End: t.pos}, true
+		}
+		if IsRawText(name) {
+			t.raw = name
+		}
+		return Token{
+			Kind:        StartTag,
+			Name:        name,
+			Attrs:       attributes(raw[len(name):]),
+			SelfClosing: strings.HasSuffix(raw, "/"),
+			Start:       start,
+			End:         t.pos,
+		}, true
+	}
+	return Token{}, false
+}
+
+// IsRawText reports whether the content of the element name is raw text, returned as a single
+// Text token up to the end tag of the element.
+func IsRawText(name string) bool {
+	return rawTextElements[name]
+}
+
+// tagEnd returns the offset of the '>' closing the tag starting at pos, ignoring quoted attribute values,
+// or -1 if the tag is not closed.
+func tagEnd(src string, pos int) int {
+	quote := byte(0)
+	for i := pos + 1; i < len(src); i++ {
+		switch {
+		case quote != 0:
+			if src[i] == quote {
+				quote = 0
+			}
+		case src[i] == '"' || src[i] == '\'':
+			quote = src[i]
+		case src[i] == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+// attributes parses the attributes following the name of a start tag.
+// When an attribute is repeated, the first value is kept, as browsers do.
+func attributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrRegex.FindAllStringSubmatch(s, -1) {
+		name := strings.ToLower(m[1])
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = html.UnescapeString(m[2] + m[3] + m[4])
+		}
+	}
+	return attrs
+}
+
+// AttrIndex returns the byte offsets of the attribute name, with its value, in the start tag tag
+// (e.g. `<p class="a">`), or false if the tag has no such attribute.
+func AttrIndex(tag, name string) (start, end int, ok bool) {
+	nameEnd := 1
+	if i := strings.IndexAny(tag, " \t\r\n/>"); i != -1 {
+		nameEnd = i
+	}
+	for _, m := range attrRegex.FindAllStringSubmatchIndex(tag[nameEnd:], -1) {
+		if strings.EqualFold(tag[nameEnd+m[2]:nameEnd+m[3]], name) {
+			return nameEnd + m[0], nameEnd + m[1], true
+		}
+	}
+	return 0, 0, false
+}
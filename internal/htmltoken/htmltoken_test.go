@@ -0,0 +1,77 @@
+package htmltoken
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizer(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Token
+	}{
+		{
+			name: "tags and text",
+			src:  `<p Class="a" class=b data-x='1 > 2'>Jo &amp; Co<br/></P>`,
+			want: []Token{
+				{Kind: StartTag, Name: "p", Attrs: map[string]string{"class": "a", "data-x": "1 > 2"}, Start: 0, End: 36},
+				{Kind: Text, Data: "Jo &amp; Co", Start: 36, End: 47},
+				{Kind: StartTag, Name: "br", Attrs: map[string]string{}, SelfClosing: true, Start: 47, End: 52},
+				{Kind: EndTag, Name: "p", Start: 52, End: 56},
+			},
+		},
+		{
+			name: "comments and doctype are skipped",
+			src:  "<!DOCTYPE html><!-- <p> -->x",
+			want: []Token{{Kind: Text, Data: "x", Start: 27, End: 28}},
+		},
+		{
+			name: "raw text",
+			src:  "<style>a > b { color: red }</STYLE><script></script>",
+			want: []Token{
+				{Kind: StartTag, Name: "style", Attrs: map[string]string{}, Start: 0, End: 7},
+				{Kind: Text, Data: "a > b { color: red }", Start: 7, End: 27},
+				{Kind: EndTag, Name: "style", Start: 27, End: 35},
+				{Kind: StartTag, Name: "script", Attrs: map[string]string{}, Start: 35, End: 43},
+				{Kind: EndTag, Name: "script", Start: 43, End: 52},
+			},
+		},
+		{
+			name: "unclosed tag is text",
+			src:  "a <b",
+			want: []Token{
+				{Kind: Text, Data: "a ", Start: 0, End: 2},
+				{Kind: Text, Data: "<b", Start: 2, End: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Token
+			tokens := New(tt.src)
+			for {
+				tok, ok := tokens.Next()
+				if !ok {
+					break
+				}
+				got = append(got, tok)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokens = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttrIndex(t *testing.T) {
+	tag := `<td class="x" STYLE='color: red'>`
+	start, end, ok := AttrIndex(tag, "style")
+	if !ok || tag[start:end] != `STYLE='color: red'` {
+		t.Errorf("AttrIndex() = %d, %d, %v", start, end, ok)
+	}
+	if _, _, ok := AttrIndex("<td/>", "style"); ok {
+		t.Errorf("AttrIndex() ok = true for a tag without the attribute")
+	}
+}
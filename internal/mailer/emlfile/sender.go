@@ -0,0 +1,154 @@
+package emlfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"mailmate/internal/mailer"
+	"mailmate/internal/mailer/mimemsg"
+	"mailmate/internal/models"
+)
+
+// DefaultFilenamePattern is used when Config.FilenamePattern is empty.
+const DefaultFilenamePattern = "{date}-{time}-{subject}.eml"
+
+// Config holds the settings of the .eml file backend.
+type Config struct {
+	// Dir is the output directory. It is created if missing. Defaults to the current directory.
+	Dir string `yaml:"dir"`
+	// FilenamePattern is the name of the generated file. The placeholders {date} (YYYY-MM-DD),
+	// {time} (HHMMSS), {subject} and {to} are replaced with values taken from the draft.
+	FilenamePattern string `yaml:"filename_pattern"`
+	// From is the optional sender address written to the From header.
+	From string `yaml:"from"`
+}
+
+// init registers the .eml file backend.
+func init() {
+	mailer.Register("eml", func(decode func(v any) error) (mailer.EmailSender, error) {
+		var cfg Config
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		return NewSender(cfg), nil
+	})
+}
+
+// EMLSender implements mailer.EmailSender by writing RFC 5322 .eml files.
+type EMLSender struct {
+	cfg Config
+	now func() time.Time
+}
+
+// NewSender creates a new instance of EMLSender.
+func NewSender(cfg Config) mailer.EmailSender {
+	return &EMLSender{cfg: cfg, now: time.Now}
+}
+
+// Send renders the draft as a MIME message and writes it to the output directory.
+// The message is flagged with "X-Unsent: 1" so that mail clients open it as a draft.
+// Existing files are never overwritten: a numeric suffix is added instead.
+func (s *EMLSender) Send(draft models.DraftEmail) error {
+	now := s.now()
+
+	msg, err := mimemsg.Build(draft, mimemsg.Options{
+		From:       s.cfg.From,
+		Date:       now,
+		IncludeBcc: true,
+		Unsent:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("building message: %w", err)
+	}
+
+	dir := s.cfg.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating output directory %q: %w", dir, err)
+	}
+
+	path, err := createUnique(filepath.Join(dir, s.filename(draft, now)), msg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Draft saved to %s\n", path)
+	return nil
+}
+
+// filename expands the configured pattern for the given draft.
+func (s *EMLSender) filename(draft models.DraftEmail, now time.Time) string {
+	pattern := s.cfg.FilenamePattern
+	if pattern == "" {
+		pattern = DefaultFilenamePattern
+	}
+
+	to := ""
+	if len(draft.To) > 0 {
+		to = draft.To[0].Address
+	}
+
+	name := strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+		"{subject}", sanitize(draft.Subject),
+		"{to}", sanitize(to),
+	).Replace(pattern)
+
+	if !strings.HasSuffix(strings.ToLower(name), ".eml") {
+		name += ".eml"
+	}
+	return name
+}
+
+// unsafeFilenameRegex matches characters that are not allowed in file names on common systems.
+var unsafeFilenameRegex = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]+`)
+
+// sanitize turns free text into a file name fragment.
+func sanitize(s string) string {
+	s = unsafeFilenameRegex.ReplaceAllString(s, "_")
+	s = strings.Join(strings.Fields(s), "_")
+	if r := []rune(s); len(r) > 80 {
+		s = string(r[:80])
+	}
+	// Trimmed once truncated, so that the name does not end with a "." (invalid on Windows)
+	s = strings.Trim(s, "._")
+	if s == "" {
+		return "draft"
+	}
+	return s
+}
+
+// createUnique writes data to path, adding a "-N" suffix before the extension if the file already exists.
+func createUnique(path string, data []byte) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	candidate := path
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			candidate = base + "-" + strconv.Itoa(i) + ext
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("creating %q: %w", candidate, err)
+		}
+		if _, err := f.Write(data); err != nil {
+			_ = f.Close()
+			return "", fmt.Errorf("writing %q: %w", candidate, err)
+		}
+		if err := f.Close(); err != nil {
+			return "", fmt.Errorf("closing %q: %w", candidate, err)
+		}
+		return candidate, nil
+	}
+}
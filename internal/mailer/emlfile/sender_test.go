@@ -0,0 +1,157 @@
+package emlfile
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mailmate/internal/models"
+)
+
+func TestSend(t *testing.T) {
+	dir := t.TempDir()
+	attachment := filepath.Join(dir, "facture.pdf")
+	if err := os.WriteFile(attachment, []byte("%PDF-1.4 fake"), 0o600); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+
+	outDir := filepath.Join(dir, "out")
+	sender := &EMLSender{
+		cfg: Config{Dir: outDir, From: "jane@example.com"},
+		now: func() time.Time { return time.Date(2026, 1, 25, 9, 30, 0, 0, time.UTC) },
+	}
+
+	draft := models.DraftEmail{
+		To:          []mail.Address{{Address: "john@example.com"}},
+		Bcc:         []mail.Address{{Address: "archive@example.com"}},
+		Subject:     "Relance facture n°42",
+		HTMLBody:    "<html><head><style>p{}</style></head><body><p>Bonjour&nbsp;John</p></body></html>",
+		TextBody:    "Bonjour John\n",
+		Attachments: []models.Attachment{{Path: attachment}},
+	}
+	for i := 0; i < 2; i++ {
+		if err := sender.Send(draft); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	// The second draft must not overwrite the first one.
+	for _, name := range []string{"2026-01-25-093000-Relance_facture_n°42.eml", "2026-01-25-093000-Relance_facture_n°42-1.eml"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("expected file %s: %v", name, err)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(outDir, "2026-01-25-093000-Relance_facture_n°42.eml"))
+	if err != nil {
+		t.Fatalf("Failed to open eml: %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != draft.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, draft.Subject)
+	}
+	if got := msg.Header.Get("Bcc"); got != "<archive@example.com>" {
+		t.Errorf("Bcc = %q, want archive address", got)
+	}
+	if got := msg.Header.Get("X-Unsent"); got != "1" {
+		t.Errorf("X-Unsent = %q, want 1", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", msg.Header.Get("Content-Type"))
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("reading body part: %v", err)
+	}
+	mediaType, params, _ = mime.ParseMediaType(body.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("first part = %q, want multipart/alternative", mediaType)
+	}
+	alt := multipart.NewReader(body, params["boundary"])
+	var types []string
+	for {
+		p, err := alt.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading alternative part: %v", err)
+		}
+		content, _ := io.ReadAll(p)
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		types = append(types, ct)
+		if ct == "text/plain" && strings.TrimSpace(string(content)) != "Bonjour John" {
+			t.Errorf("text part = %q, want %q", content, "Bonjour John")
+		}
+	}
+	if strings.Join(types, ",") != "text/plain,text/html" {
+		t.Errorf("alternative parts = %v, want text/plain then text/html", types)
+	}
+
+	att, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("reading attachment part: %v", err)
+	}
+	if att.FileName() != "facture.pdf" {
+		t.Errorf("attachment filename = %q, want facture.pdf", att.FileName())
+	}
+	content, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, att))
+	if string(content) != "%PDF-1.4 fake" {
+		t.Errorf("attachment content = %q", content)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "spaces",
+			input: "Rapport  de projet",
+			want:  "Rapport_de_projet",
+		},
+		{
+			name:  "forbidden characters",
+			input: `a/b\c:d?`,
+			want:  "a_b_c_d",
+		},
+		{
+			name:  "empty",
+			input: "  ",
+			want:  "draft",
+		},
+		{
+			name:  "truncated before a dot",
+			input: strings.Repeat("a", 79) + ". Suite",
+			want:  strings.Repeat("a", 79),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitize(tt.input); got != tt.want {
+				t.Errorf("sanitize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mailmate/internal/calendar"
+	"mailmate/internal/mailer"
+	"mailmate/internal/models"
+)
+
+// DefaultBaseURL is the Microsoft Graph v1.0 endpoint.
+const DefaultBaseURL = "https://graph.microsoft.com/v1.0"
+
+const (
+	// largeAttachmentSize is the size from which attachments are uploaded through an upload session.
+	// Graph rejects requests larger than 4 MB, and base64 inflates content by a third.
+	largeAttachmentSize = 3 * 1024 * 1024
+	// uploadChunkSize is the size of each upload session chunk. It must be a multiple of 320 KiB.
+	uploadChunkSize = 10 * 320 * 1024
+)
+
+// Config holds the settings of the Microsoft Graph backend.
+type Config struct {
+	// BaseURL is the Graph endpoint. Defaults to DefaultBaseURL.
+	BaseURL string `yaml:"base_url"`
+	// User is the mailbox owner (ID or user principal name). Defaults to the signed-in user ("me").
+	User string `yaml:"user"`
+	// Token is a static access token. If empty, the MAILMATE_GRAPH_TOKEN environment variable is used,
+	// and if that is empty too, the device code sign-in flow is used.
+	Token string `yaml:"token"`
+	// ClientID is the application (client) ID used by the device code sign-in flow.
+	ClientID string `yaml:"client_id"`
+	// Tenant is the directory (tenant) used by the device code sign-in flow. Defaults to "organizations".
+	Tenant string `yaml:"tenant"`
+	// Authority is the identity provider base URL. Defaults to DefaultAuthority.
+	Authority string `yaml:"authority"`
+	// TokenCache is the file where signed-in tokens are cached.
+	// Defaults to "mailmate/graph-token.json" in the user cache directory.
+	TokenCache string `yaml:"token_cache"`
+	// Timeout bounds the whole draft creation. Defaults to 5 minutes.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// init registers the Microsoft Graph backend.
+func init() {
+	mailer.Register("graph", func(decode func(v any) error) (mailer.EmailSender, error) {
+		var cfg Config
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Token == "" {
+			cfg.Token = os.Getenv("MAILMATE_GRAPH_TOKEN")
+		}
+		if cfg.Token != "" {
+			return NewSender(cfg, StaticToken(cfg.Token)), nil
+		}
+
+		cachePath := cfg.TokenCache
+		if cachePath == "" {
+			if dir, err := os.UserCacheDir(); err == nil {
+				cachePath = filepath.Join(dir, "mailmate", "graph-token.json")
+			}
+		}
+		return NewSender(cfg, &DeviceCodeSource{
+			Authority: cfg.Authority,
+			Tenant:    cfg.Tenant,
+			ClientID:  cfg.ClientID,
+			CachePath: cachePath,
+		}), nil
+	})
+}
+
+// GraphSender implements mailer.EmailSender by creating a draft in an Exchange Online
+// mailbox through Microsoft Graph. Like the Outlook sender, it never sends the email.
+type GraphSender struct {
+	cfg    Config
+	tokens TokenSource
+	client *http.Client
+}
+
+// NewSender creates a new instance of GraphSender using tokens to authenticate.
+func NewSender(cfg Config, tokens TokenSource) mailer.EmailSender {
+	return &GraphSender{cfg: cfg, tokens: tokens, client: http.DefaultClient}
+}
+
+// recipient is the Graph representation of an email address.
+type recipient struct {
+	EmailAddress struct {
+		Address string `json:"address"`
+		Name    string `json:"name,omitempty"`
+	} `json:"emailAddress"`
+}
+
+// recipients converts addresses to their Graph representation.
+func recipients(list []mail.Address) []recipient {
+	var out []recipient
+	for _, a := range list {
+		var r recipient
+		r.EmailAddress.Address = a.Address
+		r.EmailAddress.Name = a.Name
+		out = append(out, r)
+	}
+	return out
+}
+
+// message is the subset of the Graph message resource used to create drafts.
+type message struct {
+	Subject string `json:"subject"`
+	Body    struct {
+		ContentType string `json:"contentType"`
+		Content     string `json:"content"`
+	} `json:"body"`
+	ToRecipients               []recipient        `json:"toRecipients,omitempty"`
+	CcRecipients               []recipient        `json:"ccRecipients,omitempty"`
+	BccRecipients              []recipient        `json:"bccRecipients,omitempty"`
+	From                       *recipient         `json:"from,omitempty"`
+	ReplyTo                    []recipient        `json:"replyTo,omitempty"`
+	Importance                 string             `json:"importance,omitempty"`
+	IsReadReceiptRequested     bool               `json:"isReadReceiptRequested,omitempty"`
+	IsDeliveryReceiptRequested bool               `json:"isDeliveryReceiptRequested,omitempty"`
+	InternetMessageHeaders     []header           `json:"internetMessageHeaders,omitempty"`
+	ExtendedProperties         []extendedProperty `json:"singleValueExtendedProperties,omitempty"`
+}
+
+// header is a custom internet header of a Graph message.
+type header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// extendedProperty is a MAPI property of a Graph message, for settings the message resource does not expose.
+type extendedProperty struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+}
+
+// propSensitivity is the MAPI property PR_SENSITIVITY.
+const propSensitivity = "Integer 0x0036"
+
+// sensitivities maps sensitivity values to PR_SENSITIVITY values.
+var sensitivities = map[models.Sensitivity]string{
+	models.SensitivityNormal:       "0",
+	models.SensitivityPersonal:     "1",
+	models.SensitivityPrivate:      "2",
+	models.SensitivityConfidential: "3",
+}
+
+// Send creates the draft with POST /me/messages, then adds the attachments one by one:
+// small files inline, large files through an upload session.
+func (s *GraphSender) Send(draft models.DraftEmail) error {
+	timeout := s.cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	msg, err := newMessage(draft)
+	if err != nil {
+		return err
+	}
+	// Graph drafts cannot be meeting requests: the invitation is attached as an .ics file
+	attachments, err := calendar.WithInvitation(draft, time.Now())
+	if err != nil {
+		return err
+	}
+
+	token, err := s.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring graph token: %w", err)
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := s.do(ctx, token, http.MethodPost, s.userPath()+"/messages", msg, &created); err != nil {
+		return fmt.Errorf("creating draft: %w", err)
+	}
+	if created.ID == "" {
+		return errors.New("creating draft: graph returned no message id")
+	}
+
+	for _, a := range attachments {
+		if err := s.attach(ctx, token, created.ID, a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newMessage converts the draft to its Graph representation, without the attachments.
+func newMessage(draft models.DraftEmail) (message, error) {
+	msg := message{Subject: draft.Subject}
+	msg.Body.ContentType = "HTML"
+	msg.Body.Content = draft.HTMLBody
+	msg.ToRecipients = recipients(draft.To)
+	msg.CcRecipients = recipients(draft.Cc)
+	msg.BccRecipients = recipients(draft.Bcc)
+	if draft.From != nil {
+		msg.From = &recipients([]mail.Address{*draft.From})[0]
+	}
+	msg.ReplyTo = recipients(draft.ReplyTo)
+	msg.Importance = string(draft.Importance)
+	msg.IsReadReceiptRequested = draft.ReadReceipt
+	msg.IsDeliveryReceiptRequested = draft.DeliveryReceipt
+	if v, ok := sensitivities[draft.Sensitivity]; ok {
+		msg.ExtendedProperties = []extendedProperty{{ID: propSensitivity, Value: v}}
+	}
+	names := make([]string, 0, len(draft.Headers))
+	for name := range draft.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Graph only accepts custom headers starting with "X-"
+		if !strings.HasPrefix(strings.ToLower(name), "x-") {
+			return message{}, fmt.Errorf("header %q is not supported by the graph backend: custom headers must start with X-", name)
+		}
+		msg.InternetMessageHeaders = append(msg.InternetMessageHeaders, header{Name: name, Value: draft.Headers[name]})
+	}
+	return msg, nil
+}
+
+// attach adds the attachment to the draft identified by messageID.
+// Inline attachments are flagged with isInline and their content ID so that "cid:" references resolve.
+func (s *GraphSender) attach(ctx context.Context, token, messageID string, a models.Attachment) error {
+	name := a.FileName()
+	data, err := a.Read()
+	if err != nil {
+		return fmt.Errorf("reading attachment %s: %w", name, err)
+	}
+	contentType := a.MediaType()
+	attachmentsPath := s.userPath() + "/messages/" + url.PathEscape(messageID) + "/attachments"
+
+	if len(data) < largeAttachmentSize {
+		body := map[string]any{
+			"@odata.type":  "#microsoft.graph.fileAttachment",
+			"name":         name,
+			"contentType":  contentType,
+			"contentBytes": base64.StdEncoding.EncodeToString(data),
+		}
+		if a.Inline() {
+			body["isInline"] = true
+			body["contentId"] = a.ContentID
+		}
+		if err := s.do(ctx, token, http.MethodPost, attachmentsPath, body, nil); err != nil {
+			return fmt.Errorf("adding attachment %s: %w", name, err)
+		}
+		return nil
+	}
+
+	var session struct {
+		UploadURL string `json:"uploadUrl"`
+	}
+	item := map[string]any{
+		"attachmentType": "file",
+		"name":           name,
+		"size":           len(data),
+		"contentType":    contentType,
+	}
+	if a.Inline() {
+		item["isInline"] = true
+		item["contentId"] = a.ContentID
+	}
+	body := map[string]any{"AttachmentItem": item}
+	if err := s.do(ctx, token, http.MethodPost, attachmentsPath+"/createUploadSession", body, &session); err != nil {
+		return fmt.Errorf("creating upload session for %s: %w", name, err)
+	}
+	if session.UploadURL == "" {
+		return fmt.Errorf("creating upload session for %s: graph returned no upload url", name)
+	}
+
+	for start := 0; start < len(data); start += uploadChunkSize {
+		end := min(start+uploadChunkSize, len(data))
+		if err := s.uploadChunk(ctx, session.UploadURL, data[start:end], start, len(data)); err != nil {
+			return fmt.Errorf("uploading attachment %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// uploadChunk sends one byte range of an upload session.
+// The upload URL is pre-authenticated: sending the Authorization header is an error.
+func (s *GraphSender) uploadChunk(ctx context.Context, uploadURL string, chunk []byte, offset, total int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader(chunk))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+len(chunk)-1, total))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// do sends a JSON request to Graph and decodes the JSON response into out, if not nil.
+func (s *GraphSender) do(ctx context.Context, token, method, path string, in, out any) error {
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	baseURL := s.cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(baseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// userPath returns the path of the mailbox owner.
+func (s *GraphSender) userPath() string {
+	if s.cfg.User == "" {
+		return "/me"
+	}
+	return "/users/" + url.PathEscape(s.cfg.User)
+}
+
+// responseError builds an error from a failed Graph response, using the Graph error message when present.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var graphErr struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &graphErr) == nil && graphErr.Error.Code != "" {
+		return fmt.Errorf("graph returned %s: %s: %s", resp.Status, graphErr.Error.Code, graphErr.Error.Message)
+	}
+	return fmt.Errorf("graph returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,246 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mailmate/internal/models"
+)
+
+// fakeGraph is an httptest stand-in for the Graph endpoints used by GraphSender.
+type fakeGraph struct {
+	mu          sync.Mutex
+	server      *httptest.Server
+	message     map[string]any
+	attachments []map[string]any
+	uploaded    []byte
+	ranges      []string
+	authHeaders []string
+}
+
+func newFakeGraph(t *testing.T) *fakeGraph {
+	t.Helper()
+	g := &fakeGraph{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1.0/me/messages", func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.authHeaders = append(g.authHeaders, r.Header.Get("Authorization"))
+		_ = json.NewDecoder(r.Body).Decode(&g.message)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"id":"AAMk=="}`)
+	})
+	mux.HandleFunc("POST /v1.0/me/messages/{id}/attachments", func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		var a map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&a)
+		g.attachments = append(g.attachments, a)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{}`)
+	})
+	mux.HandleFunc("POST /v1.0/me/messages/{id}/attachments/createUploadSession", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"uploadUrl":"`+g.server.URL+`/upload/session-1"}`)
+	})
+	mux.HandleFunc("PUT /upload/session-1", func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if r.Header.Get("Authorization") != "" {
+			http.Error(w, "upload urls must not be authenticated", http.StatusUnauthorized)
+			return
+		}
+		chunk, _ := io.ReadAll(r.Body)
+		g.uploaded = append(g.uploaded, chunk...)
+		g.ranges = append(g.ranges, r.Header.Get("Content-Range"))
+		w.WriteHeader(http.StatusOK)
+	})
+	g.server = httptest.NewServer(mux)
+	t.Cleanup(g.server.Close)
+	return g
+}
+
+func TestSend(t *testing.T) {
+	g := newFakeGraph(t)
+
+	dir := t.TempDir()
+	small := filepath.Join(dir, "cgv.pdf")
+	if err := os.WriteFile(small, []byte("%PDF-1.4 fake"), 0o600); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+	large := filepath.Join(dir, "video.bin")
+	largeContent := []byte(strings.Repeat("x", uploadChunkSize+1024))
+	if err := os.WriteFile(large, largeContent, 0o600); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+
+	sender := NewSender(Config{BaseURL: g.server.URL + "/v1.0"}, StaticToken("secret-token"))
+	draft := models.DraftEmail{
+		To:       []mail.Address{{Name: "John", Address: "john@example.com"}, {Address: "marie@example.com"}},
+		Bcc:      []mail.Address{{Address: "archive@example.com"}},
+		Subject:  "Relance",
+		HTMLBody: "<p>Bonjour</p>",
+		Attachments: []models.Attachment{
+			{Path: small},
+			{Path: large},
+			{Content: []byte("\x89PNG fake"), Name: "logo.png", ContentID: "logo@mailmate"},
+		},
+	}
+	if err := sender.Send(draft); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.authHeaders) != 1 || g.authHeaders[0] != "Bearer secret-token" {
+		t.Errorf("Authorization headers = %v", g.authHeaders)
+	}
+	if g.message["subject"] != "Relance" {
+		t.Errorf("subject = %v", g.message["subject"])
+	}
+	body := g.message["body"].(map[string]any)
+	if body["contentType"] != "HTML" || body["content"] != "<p>Bonjour</p>" {
+		t.Errorf("body = %v", body)
+	}
+	to := g.message["toRecipients"].([]any)
+	if len(to) != 2 {
+		t.Fatalf("toRecipients = %v", to)
+	}
+	first := to[0].(map[string]any)["emailAddress"].(map[string]any)
+	if first["address"] != "john@example.com" || first["name"] != "John" {
+		t.Errorf("first recipient = %v", first)
+	}
+	if _, ok := g.message["bccRecipients"]; !ok {
+		t.Errorf("bccRecipients missing")
+	}
+
+	if len(g.attachments) != 2 {
+		t.Fatalf("got %d small attachments, want 2", len(g.attachments))
+	}
+	content, _ := base64.StdEncoding.DecodeString(g.attachments[0]["contentBytes"].(string))
+	if g.attachments[0]["name"] != "cgv.pdf" || string(content) != "%PDF-1.4 fake" {
+		t.Errorf("attachment = %v", g.attachments[0])
+	}
+	if _, ok := g.attachments[0]["isInline"]; ok {
+		t.Errorf("regular attachment must not be inline: %v", g.attachments[0])
+	}
+	logo := g.attachments[1]
+	if logo["name"] != "logo.png" || logo["contentType"] != "image/png" || logo["isInline"] != true || logo["contentId"] != "logo@mailmate" {
+		t.Errorf("inline attachment = %v", logo)
+	}
+
+	if string(g.uploaded) != string(largeContent) {
+		t.Errorf("uploaded %d bytes, want %d", len(g.uploaded), len(largeContent))
+	}
+	if len(g.ranges) != 2 || !strings.HasPrefix(g.ranges[0], "bytes 0-") {
+		t.Errorf("Content-Range headers = %v", g.ranges)
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	draft := models.DraftEmail{
+		From:            &mail.Address{Name: "Support", Address: "support@example.com"},
+		ReplyTo:         []mail.Address{{Address: "tickets@example.com"}},
+		Subject:         "Relance",
+		Importance:      models.ImportanceHigh,
+		Sensitivity:     models.SensitivityPrivate,
+		ReadReceipt:     true,
+		DeliveryReceipt: true,
+		Headers:         map[string]string{"X-Campaign": "relance"},
+	}
+	msg, err := newMessage(draft)
+	if err != nil {
+		t.Fatalf("newMessage() error = %v", err)
+	}
+	if msg.From == nil || msg.From.EmailAddress.Address != "support@example.com" || len(msg.ReplyTo) != 1 {
+		t.Errorf("from = %v, replyTo = %v", msg.From, msg.ReplyTo)
+	}
+	if msg.Importance != "high" || !msg.IsReadReceiptRequested || !msg.IsDeliveryReceiptRequested {
+		t.Errorf("message options = %+v", msg)
+	}
+	if len(msg.ExtendedProperties) != 1 || msg.ExtendedProperties[0] != (extendedProperty{ID: propSensitivity, Value: "2"}) {
+		t.Errorf("singleValueExtendedProperties = %v", msg.ExtendedProperties)
+	}
+	if len(msg.InternetMessageHeaders) != 1 || msg.InternetMessageHeaders[0] != (header{Name: "X-Campaign", Value: "relance"}) {
+		t.Errorf("internetMessageHeaders = %v", msg.InternetMessageHeaders)
+	}
+
+	draft.Headers = map[string]string{"List-Unsubscribe": "<mailto:stop@example.com>"}
+	if _, err := newMessage(draft); err == nil {
+		t.Errorf("newMessage() error = nil, want an error for a header without the X- prefix")
+	}
+}
+
+func TestDeviceCodeSource(t *testing.T) {
+	defaultPollInterval = 0
+	t.Cleanup(func() { defaultPollInterval = 5 * time.Second })
+
+	var mu sync.Mutex
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /contoso/oauth2/v2.0/devicecode", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "app-id" {
+			http.Error(w, "bad client", http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(w, `{"device_code":"dc","user_code":"ABCD","verification_uri":"https://example.com/device","expires_in":60,"interval":0}`)
+	})
+	mux.HandleFunc("POST /contoso/oauth2/v2.0/token", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.FormValue("grant_type") {
+		case "refresh_token":
+			_, _ = io.WriteString(w, `{"access_token":"refreshed","expires_in":3600}`)
+		default:
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"error":"authorization_pending"}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"access_token":"first","refresh_token":"rt","expires_in":0}`)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	var prompt string
+	src := &DeviceCodeSource{
+		Authority: srv.URL,
+		Tenant:    "contoso",
+		ClientID:  "app-id",
+		CachePath: filepath.Join(t.TempDir(), "token.json"),
+		Prompt:    func(m string) { prompt = m },
+	}
+
+	token, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token != "first" {
+		t.Errorf("Token() = %q, want first", token)
+	}
+	if !strings.Contains(prompt, "ABCD") {
+		t.Errorf("prompt = %q, want user code", prompt)
+	}
+
+	// The first token expired immediately: the cached refresh token must be used.
+	token, err = src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token != "refreshed" {
+		t.Errorf("Token() = %q, want refreshed", token)
+	}
+}
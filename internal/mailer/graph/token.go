@@ -0,0 +1,254 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TokenSource supplies OAuth 2.0 access tokens for Microsoft Graph.
+type TokenSource interface {
+	// Token returns a valid access token.
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource always returning the same access token.
+// It is useful in automation, where the token is obtained by another tool.
+type StaticToken string
+
+// Token returns the static token.
+func (t StaticToken) Token(context.Context) (string, error) {
+	if t == "" {
+		return "", errors.New("graph access token is empty")
+	}
+	return string(t), nil
+}
+
+// DefaultAuthority is the Microsoft identity platform endpoint.
+const DefaultAuthority = "https://login.microsoftonline.com"
+
+// defaultPollInterval is the device code polling interval used when the server does not provide one.
+var defaultPollInterval = 5 * time.Second
+
+// graphScopes are the delegated permissions needed to create drafts.
+const graphScopes = "offline_access https://graph.microsoft.com/Mail.ReadWrite"
+
+// DeviceCodeSource is a TokenSource using the OAuth 2.0 device authorization grant:
+// the user is asked to open a URL and enter a code, once. The refresh token is then
+// cached on disk so that subsequent runs are silent.
+type DeviceCodeSource struct {
+	// Authority is the identity provider base URL. Defaults to DefaultAuthority.
+	Authority string
+	// Tenant is the directory (tenant) ID or domain. Defaults to "organizations".
+	Tenant string
+	// ClientID is the application (client) ID registered in Entra ID.
+	ClientID string
+	// CachePath is the file where tokens are cached. Caching is disabled if empty.
+	CachePath string
+	// Prompt receives the instructions to display to the user. Defaults to printing on stdout.
+	Prompt func(message string)
+	// HTTPClient is the client used to reach the authority. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// cachedToken is the on-disk representation of the token cache.
+type cachedToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// tokenResponse is the token endpoint response, successful or not.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Token returns a cached access token if still valid, refreshes it if possible,
+// and falls back to the interactive device code flow otherwise.
+func (s *DeviceCodeSource) Token(ctx context.Context) (string, error) {
+	if s.ClientID == "" {
+		return "", errors.New("graph client_id is not configured")
+	}
+
+	cached := s.loadCache()
+	if cached.AccessToken != "" && time.Now().Add(time.Minute).Before(cached.Expiry) {
+		return cached.AccessToken, nil
+	}
+
+	if cached.RefreshToken != "" {
+		resp, err := s.postToken(ctx, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {cached.RefreshToken},
+			"scope":         {graphScopes},
+		})
+		if err == nil && resp.Error == "" {
+			return s.store(resp, cached.RefreshToken)
+		}
+		// The refresh token expired or was revoked: ask the user again.
+	}
+
+	return s.deviceFlow(ctx)
+}
+
+// deviceFlow runs the device authorization grant (RFC 8628).
+func (s *DeviceCodeSource) deviceFlow(ctx context.Context) (string, error) {
+	var code struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+		Message         string `json:"message"`
+	}
+	if err := s.post(ctx, "devicecode", url.Values{"scope": {graphScopes}}, &code); err != nil {
+		return "", fmt.Errorf("requesting device code: %w", err)
+	}
+	if code.DeviceCode == "" {
+		return "", errors.New("requesting device code: empty response")
+	}
+
+	message := code.Message
+	if message == "" {
+		message = fmt.Sprintf("To sign in, open %s and enter the code %s", code.VerificationURI, code.UserCode)
+	}
+	if s.Prompt != nil {
+		s.Prompt(message)
+	} else {
+		fmt.Println(message)
+	}
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	for {
+		resp, err := s.postToken(ctx, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {code.DeviceCode},
+		})
+		if err != nil {
+			return "", err
+		}
+		switch resp.Error {
+		case "":
+			return s.store(resp, "")
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return "", fmt.Errorf("device code sign-in failed: %s: %s", resp.Error, resp.ErrorDescription)
+		}
+
+		if code.ExpiresIn > 0 && time.Now().After(deadline) {
+			return "", errors.New("device code sign-in expired")
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// store caches a successful token response and returns its access token.
+func (s *DeviceCodeSource) store(resp *tokenResponse, previousRefresh string) (string, error) {
+	cached := cachedToken{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}
+	if cached.RefreshToken == "" {
+		cached.RefreshToken = previousRefresh
+	}
+
+	if s.CachePath != "" {
+		data, err := json.Marshal(cached)
+		if err == nil {
+			if err := os.MkdirAll(filepath.Dir(s.CachePath), 0o700); err == nil {
+				// A failing cache only means the user will be asked to sign in again.
+				_ = os.WriteFile(s.CachePath, data, 0o600)
+			}
+		}
+	}
+	return cached.AccessToken, nil
+}
+
+// loadCache reads the token cache. A missing or corrupt cache yields an empty token.
+func (s *DeviceCodeSource) loadCache() cachedToken {
+	var cached cachedToken
+	if s.CachePath == "" {
+		return cached
+	}
+	data, err := os.ReadFile(s.CachePath)
+	if err != nil {
+		return cached
+	}
+	_ = json.Unmarshal(data, &cached)
+	return cached
+}
+
+// postToken calls the token endpoint. OAuth errors are reported in the response, not as err.
+func (s *DeviceCodeSource) postToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	var resp tokenResponse
+	if err := s.post(ctx, "token", form, &resp); err != nil {
+		return nil, fmt.Errorf("requesting token: %w", err)
+	}
+	return &resp, nil
+}
+
+// post sends a form to the given OAuth endpoint and decodes the JSON response into out.
+// OAuth error responses (HTTP 400 with an "error" field) are decoded as well.
+func (s *DeviceCodeSource) post(ctx context.Context, endpoint string, form url.Values, out any) error {
+	authority := s.Authority
+	if authority == "" {
+		authority = DefaultAuthority
+	}
+	tenant := s.Tenant
+	if tenant == "" {
+		tenant = "organizations"
+	}
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	form.Set("client_id", s.ClientID)
+	u := strings.TrimRight(authority, "/") + "/" + url.PathEscape(tenant) + "/oauth2/v2.0/" + endpoint
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package imapdraft
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf16"
+)
+
+// client is a minimal IMAP4rev1 client implementing the few commands needed to
+// store a draft: STARTTLS, LOGIN, CREATE, APPEND and LOGOUT.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// newClient wraps conn and reads the server greeting.
+func newClient(conn net.Conn) (*client, error) {
+	c := &client{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		return nil, fmt.Errorf("reading imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		return nil, fmt.Errorf("unexpected imap greeting: %q", greeting)
+	}
+	return c, nil
+}
+
+// startTLS upgrades the connection using the STARTTLS command.
+func (c *client) startTLS(cfg *tls.Config) error {
+	if _, err := c.command("STARTTLS"); err != nil {
+		return err
+	}
+	tlsConn := tls.Client(c.conn, cfg)
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+	c.conn = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// login authenticates with the LOGIN command.
+// Quoted strings only allow 7-bit characters: credentials with non-ASCII characters
+// are sent as literals.
+func (c *client) login(username, password string) error {
+	tag := c.nextTag()
+	line := tag + " LOGIN"
+	for _, s := range []string{username, password} {
+		if isASCII(s) {
+			q, err := quote(s)
+			if err != nil {
+				return err
+			}
+			line += " " + q
+			continue
+		}
+		if _, err := fmt.Fprintf(c.conn, "%s {%d}\r\n", line, len(s)); err != nil {
+			return fmt.Errorf("sending LOGIN: %w", err)
+		}
+		if err := c.waitContinuation(tag, "LOGIN"); err != nil {
+			return err
+		}
+		line = s
+	}
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", line); err != nil {
+		return fmt.Errorf("sending LOGIN: %w", err)
+	}
+	_, err := c.waitTagged(tag)
+	return err
+}
+
+// create creates a mailbox.
+func (c *client) create(mailbox string) error {
+	name, err := quote(encodeMailbox(mailbox))
+	if err != nil {
+		return err
+	}
+	_, err = c.command("CREATE " + name)
+	return err
+}
+
+// appendMessage uploads msg to mailbox with the given flags.
+func (c *client) appendMessage(mailbox string, flags []string, msg []byte) error {
+	name, err := quote(encodeMailbox(mailbox))
+	if err != nil {
+		return err
+	}
+
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s APPEND %s (%s) {%d}\r\n", tag, name, strings.Join(flags, " "), len(msg)); err != nil {
+		return fmt.Errorf("sending APPEND: %w", err)
+	}
+
+	if err := c.waitContinuation(tag, "APPEND"); err != nil {
+		return err
+	}
+
+	if _, err := c.conn.Write(msg); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+	if _, err := c.conn.Write([]byte("\r\n")); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+
+	_, err = c.waitTagged(tag)
+	return err
+}
+
+// logout ends the session and closes the connection.
+func (c *client) logout() error {
+	_, err := c.command("LOGOUT")
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// close closes the connection without logging out.
+func (c *client) close() {
+	_ = c.conn.Close()
+}
+
+// command sends a tagged command and waits for its completion.
+func (c *client) command(cmd string) (string, error) {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, cmd); err != nil {
+		return "", fmt.Errorf("sending %s: %w", strings.Fields(cmd)[0], err)
+	}
+	return c.waitTagged(tag)
+}
+
+// waitContinuation waits for the continuation request allowing to send a literal
+// of the command cmd tagged tag.
+func (c *client) waitContinuation(tag, cmd string) error {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return fmt.Errorf("reading %s response: %w", cmd, err)
+		}
+		if strings.HasPrefix(line, "+") {
+			return nil
+		}
+		if strings.HasPrefix(line, tag+" ") {
+			if err := responseError(tag, line); err != nil {
+				return err
+			}
+			return fmt.Errorf("imap server completed %s before the literal", cmd)
+		}
+	}
+}
+
+// waitTagged reads responses until the tagged completion for tag and returns it.
+func (c *client) waitTagged(tag string) (string, error) {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return "", fmt.Errorf("reading imap response: %w", err)
+		}
+		if strings.HasPrefix(line, tag+" ") {
+			return line, responseError(tag, line)
+		}
+	}
+}
+
+// readLine reads a single CRLF terminated response line.
+func (c *client) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *client) nextTag() string {
+	c.tag++
+	return fmt.Sprintf("a%03d", c.tag)
+}
+
+// statusError is returned when the server answers NO or BAD to a command.
+type statusError struct {
+	status string
+	text   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("imap server replied %s: %s", e.status, e.text)
+}
+
+// isTryCreate reports whether err asks the client to create the mailbox first (RFC 3501 TRYCREATE).
+func isTryCreate(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && strings.Contains(strings.ToUpper(se.text), "[TRYCREATE]")
+}
+
+// responseError converts a tagged response line into an error unless it is OK.
+func responseError(tag, line string) error {
+	rest := strings.TrimPrefix(line, tag+" ")
+	status, text, _ := strings.Cut(rest, " ")
+	if strings.EqualFold(status, "OK") {
+		return nil
+	}
+	return &statusError{status: strings.ToUpper(status), text: text}
+}
+
+// quote formats s as an IMAP quoted string.
+func quote(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return "", errors.New("imap strings cannot contain line breaks")
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`, nil
+}
+
+// isASCII reports whether s only holds 7-bit characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeMailbox encodes a mailbox name in the modified UTF-7 of RFC 3501 section 5.1.3.
+func encodeMailbox(name string) string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+,"
+
+	var b strings.Builder
+	var pending []rune
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		units := utf16.Encode(pending)
+		raw := make([]byte, 0, len(units)*2)
+		for _, u := range units {
+			raw = append(raw, byte(u>>8), byte(u))
+		}
+		b.WriteByte('&')
+		var bits, nbits uint
+		for _, c := range raw {
+			bits = bits<<8 | uint(c)
+			nbits += 8
+			for nbits >= 6 {
+				nbits -= 6
+				b.WriteByte(alphabet[(bits>>nbits)&0x3f])
+			}
+		}
+		if nbits > 0 {
+			b.WriteByte(alphabet[(bits<<(6-nbits))&0x3f])
+		}
+		b.WriteByte('-')
+		pending = pending[:0]
+	}
+
+	for _, r := range name {
+		switch {
+		case r == '&':
+			flush()
+			b.WriteString("&-")
+		case r >= 0x20 && r <= 0x7e:
+			flush()
+			b.WriteRune(r)
+		default:
+			pending = append(pending, r)
+		}
+	}
+	flush()
+	return b.String()
+}
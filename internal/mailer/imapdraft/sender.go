@@ -0,0 +1,190 @@
+package imapdraft
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mailmate/internal/mailer"
+	"mailmate/internal/mailer/mimemsg"
+	"mailmate/internal/models"
+)
+
+// Security modes supported by the IMAP backend.
+const (
+	// SecurityTLS connects over implicit TLS (usually port 993).
+	SecurityTLS = "tls"
+	// SecurityStartTLS connects in plain text and upgrades the connection with STARTTLS.
+	SecurityStartTLS = "starttls"
+	// SecurityNone never encrypts the connection. Only use it with local servers.
+	SecurityNone = "none"
+)
+
+// DefaultMailbox is the mailbox used when Config.Mailbox is empty.
+const DefaultMailbox = "Drafts"
+
+// Config holds the settings required to store drafts on an IMAP server.
+type Config struct {
+	// Host is the IMAP server host name.
+	Host string `yaml:"host"`
+	// Port is the IMAP server port. Defaults to 993, or 143 without implicit TLS.
+	Port int `yaml:"port"`
+	// Username is the login used for authentication.
+	Username string `yaml:"username"`
+	// Password is the secret used for authentication.
+	// If empty, the MAILMATE_IMAP_PASSWORD environment variable is used.
+	Password string `yaml:"password"`
+	// Mailbox is the drafts mailbox (e.g. "Drafts", "Brouillons"). Defaults to "Drafts".
+	// It is created if the server reports that it does not exist.
+	Mailbox string `yaml:"mailbox"`
+	// From is the optional sender address written to the From header.
+	From string `yaml:"from"`
+	// Security is one of SecurityTLS (default), SecurityStartTLS or SecurityNone.
+	Security string `yaml:"security"`
+	// InsecureSkipVerify disables TLS certificate verification. Only use it for testing.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// Timeout bounds the whole session. Defaults to 60 seconds.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// init registers the IMAP backend.
+func init() {
+	mailer.Register("imap", func(decode func(v any) error) (mailer.EmailSender, error) {
+		var cfg Config
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Password == "" {
+			cfg.Password = os.Getenv("MAILMATE_IMAP_PASSWORD")
+		}
+		return NewSender(cfg), nil
+	})
+}
+
+// IMAPSender implements mailer.EmailSender by uploading the message to the drafts
+// mailbox of an IMAP server. Like the Outlook sender, it never sends anything:
+// the user reviews the draft in their mail client and sends it from there.
+type IMAPSender struct {
+	cfg Config
+}
+
+// NewSender creates a new instance of IMAPSender.
+func NewSender(cfg Config) mailer.EmailSender {
+	return &IMAPSender{cfg: cfg}
+}
+
+// Send renders the draft as a MIME message and stores it with APPEND and the \Draft flag.
+func (s *IMAPSender) Send(draft models.DraftEmail) error {
+	if s.cfg.Host == "" {
+		return errors.New("imap host is not configured")
+	}
+	if s.cfg.Username == "" {
+		return errors.New("imap username is not configured")
+	}
+
+	msg, err := mimemsg.Build(draft, mimemsg.Options{
+		From:       s.cfg.From,
+		IncludeBcc: true,
+	})
+	if err != nil {
+		return fmt.Errorf("building message: %w", err)
+	}
+
+	mailbox := s.cfg.Mailbox
+	if mailbox == "" {
+		mailbox = DefaultMailbox
+	}
+
+	c, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	if err := c.login(s.cfg.Username, s.cfg.Password); err != nil {
+		return fmt.Errorf("imap login failed: %w", err)
+	}
+
+	flags := []string{`\Draft`, `\Seen`}
+	err = c.appendMessage(mailbox, flags, msg)
+	if isTryCreate(err) {
+		if err := c.create(mailbox); err != nil {
+			return fmt.Errorf("creating mailbox %q: %w", mailbox, err)
+		}
+		err = c.appendMessage(mailbox, flags, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("saving draft to %q: %w", mailbox, err)
+	}
+
+	if err := c.logout(); err != nil {
+		return fmt.Errorf("imap logout failed: %w", err)
+	}
+	return nil
+}
+
+// dial opens a connection to the server and negotiates TLS according to the configured security mode.
+func (s *IMAPSender) dial() (*client, error) {
+	security := strings.ToLower(s.cfg.Security)
+	if security == "" {
+		security = SecurityTLS
+	}
+
+	port := s.cfg.Port
+	if port == 0 {
+		port = 143
+		if security == SecurityTLS {
+			port = 993
+		}
+	}
+
+	timeout := s.cfg.Timeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{
+		ServerName:         s.cfg.Host,
+		InsecureSkipVerify: s.cfg.InsecureSkipVerify, //nolint:gosec // opt-in for testing only
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	switch security {
+	case SecurityTLS:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	case SecurityStartTLS, SecurityNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown imap security mode: %s", s.cfg.Security)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to imap server %s: %w", addr, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("configuring imap connection: %w", err)
+	}
+
+	c, err := newClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if security == SecurityStartTLS {
+		if err := c.startTLS(tlsConfig); err != nil {
+			c.close()
+			return nil, fmt.Errorf("imap STARTTLS failed: %w", err)
+		}
+	}
+
+	return c, nil
+}
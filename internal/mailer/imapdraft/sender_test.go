@@ -0,0 +1,282 @@
+package imapdraft
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"mailmate/internal/models"
+)
+
+// stubServer is a minimal in-process IMAP server supporting LOGIN, CREATE, APPEND and LOGOUT.
+type stubServer struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	mailboxes map[string]bool
+	login     string
+	appended  map[string][]string
+	flags     string
+}
+
+func newStubServer(t *testing.T, mailboxes ...string) *stubServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &stubServer{
+		listener:  l,
+		mailboxes: make(map[string]bool),
+		appended:  make(map[string][]string),
+	}
+	for _, m := range mailboxes {
+		s.mailboxes[m] = true
+	}
+	t.Cleanup(func() {
+		if err := l.Close(); err != nil {
+			t.Logf("failed to close listener: %v", err)
+		}
+	})
+	go s.serve()
+	return s
+}
+
+func (s *stubServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *stubServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		_, _ = fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("* OK stub IMAP4rev1 ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := splitArgs(strings.TrimRight(line, "\r\n"))
+		if len(fields) < 2 {
+			continue
+		}
+		tag, cmd := fields[0], strings.ToUpper(fields[1])
+
+		s.mu.Lock()
+		switch cmd {
+		case "LOGIN":
+			// Literal arguments are recorded as their raw content
+			args := fields[2:]
+			for len(args) > 0 && isLiteral(args[len(args)-1]) {
+				size, _ := strconv.Atoi(strings.Trim(args[len(args)-1], "{}"))
+				reply("+ Ready for literal data")
+				data := make([]byte, size)
+				s.mu.Unlock()
+				_, err := io.ReadFull(r, data)
+				rest, restErr := r.ReadString('\n')
+				s.mu.Lock()
+				if err != nil || restErr != nil {
+					s.mu.Unlock()
+					return
+				}
+				args[len(args)-1] = string(data)
+				if rest = strings.TrimRight(rest, "\r\n"); rest != "" {
+					args = append(args, splitArgs(strings.TrimPrefix(rest, " "))...)
+				}
+			}
+			s.login = strings.Join(args, " ")
+			reply("%s OK LOGIN completed", tag)
+		case "CREATE":
+			s.mailboxes[unquote(fields[2])] = true
+			reply("%s OK CREATE completed", tag)
+		case "APPEND":
+			mailbox := unquote(fields[2])
+			size, _ := strconv.Atoi(strings.Trim(fields[len(fields)-1], "{}"))
+			if !s.mailboxes[mailbox] {
+				reply("%s NO [TRYCREATE] Mailbox does not exist", tag)
+				break
+			}
+			reply("+ Ready for literal data")
+			data := make([]byte, size+2)
+			s.mu.Unlock()
+			_, err := io.ReadFull(r, data)
+			s.mu.Lock()
+			if err != nil {
+				s.mu.Unlock()
+				return
+			}
+			s.flags = strings.Join(fields[3:len(fields)-1], " ")
+			s.appended[mailbox] = append(s.appended[mailbox], string(data[:size]))
+			reply("%s OK APPEND completed", tag)
+		case "LOGOUT":
+			reply("* BYE")
+			reply("%s OK LOGOUT completed", tag)
+			s.mu.Unlock()
+			return
+		default:
+			reply("%s BAD unknown command", tag)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// splitArgs splits a command line on spaces, keeping quoted strings and parenthesized lists together.
+func splitArgs(line string) []string {
+	var args []string
+	var cur strings.Builder
+	inQuote, depth := false, 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuote && c == '\\' && i+1 < len(line):
+			cur.WriteByte(c)
+			i++
+			cur.WriteByte(line[i])
+			continue
+		case c == '"':
+			inQuote = !inQuote
+		case !inQuote && c == '(':
+			depth++
+		case !inQuote && c == ')':
+			depth--
+		case !inQuote && depth == 0 && c == ' ':
+			args = append(args, cur.String())
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(c)
+	}
+	return append(args, cur.String())
+}
+
+// isLiteral reports whether a command argument announces a literal ("{12}").
+func isLiteral(arg string) bool {
+	if len(arg) < 3 || arg[0] != '{' || arg[len(arg)-1] != '}' {
+		return false
+	}
+	_, err := strconv.Atoi(arg[1 : len(arg)-1])
+	return err == nil
+}
+
+// unquote removes the quotes of an IMAP quoted string.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s)
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name      string
+		mailbox   string
+		password  string
+		existing  []string
+		wantLogin string
+		wantStore string
+	}{
+		{
+			name:      "default mailbox",
+			password:  `pa"ss`,
+			existing:  []string{"Drafts"},
+			wantLogin: `"jane" "pa\"ss"`,
+			wantStore: "Drafts",
+		},
+		{
+			name:      "mailbox created on demand, non-ASCII password",
+			mailbox:   "Éléments brouillons",
+			password:  "mötdepässe",
+			wantLogin: `"jane" mötdepässe`,
+			wantStore: "&AMk-l&AOk-ments brouillons",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newStubServer(t, tt.existing...)
+			sender := NewSender(Config{
+				Host:     "127.0.0.1",
+				Port:     srv.port(),
+				Username: "jane",
+				Password: tt.password,
+				Mailbox:  tt.mailbox,
+				Security: SecurityNone,
+			})
+
+			draft := models.DraftEmail{
+				To:       []mail.Address{{Address: "john@example.com"}},
+				Bcc:      []mail.Address{{Address: "archive@example.com"}},
+				Subject:  "Relance",
+				HTMLBody: "<p>Bonjour</p>",
+			}
+			if err := sender.Send(draft); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+
+			if srv.login != tt.wantLogin {
+				t.Errorf("LOGIN arguments = %s", srv.login)
+			}
+			if srv.flags != `(\Draft \Seen)` {
+				t.Errorf("APPEND flags = %s", srv.flags)
+			}
+			msgs := srv.appended[tt.wantStore]
+			if len(msgs) != 1 {
+				t.Fatalf("mailbox %q holds %d messages, want 1 (all: %v)", tt.wantStore, len(msgs), srv.appended)
+			}
+			for _, want := range []string{"To: <john@example.com>", "Bcc: <archive@example.com>", "Subject: Relance"} {
+				if !strings.Contains(msgs[0], want) {
+					t.Errorf("message does not contain %q:\n%s", want, msgs[0])
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeMailbox(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "ascii",
+			input: "Drafts",
+			want:  "Drafts",
+		},
+		{
+			name:  "ampersand",
+			input: "R&D",
+			want:  "R&-D",
+		},
+		{
+			name:  "accented",
+			input: "Éléments envoyés",
+			want:  "&AMk-l&AOk-ments envoy&AOk-s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeMailbox(tt.input); got != tt.want {
+				t.Errorf("encodeMailbox() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
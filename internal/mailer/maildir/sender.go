@@ -0,0 +1,137 @@
+package maildir
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"mailmate/internal/homedir"
+	"mailmate/internal/mailer"
+	"mailmate/internal/mailer/mimemsg"
+	"mailmate/internal/models"
+)
+
+// DefaultFolder is the drafts folder used when Config.Folder is empty.
+const DefaultFolder = "Drafts"
+
+// Config holds the settings of the Maildir backend.
+type Config struct {
+	// Path is the root of the Maildir (e.g. "~/Mail/work").
+	Path string `yaml:"path"`
+	// Folder is the drafts folder relative to Path. Defaults to "Drafts".
+	// Use ".Drafts" for Maildir++ layouts such as Dovecot's.
+	Folder string `yaml:"folder"`
+	// From is the optional sender address written to the From header.
+	From string `yaml:"from"`
+}
+
+// init registers the Maildir backend.
+func init() {
+	mailer.Register("maildir", func(decode func(v any) error) (mailer.EmailSender, error) {
+		var cfg Config
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		return NewSender(cfg), nil
+	})
+}
+
+// MaildirSender implements mailer.EmailSender by storing drafts in a Maildir folder,
+// where mail clients such as mutt, aerc or notmuch pick them up.
+type MaildirSender struct {
+	cfg Config
+}
+
+// NewSender creates a new instance of MaildirSender.
+func NewSender(cfg Config) mailer.EmailSender {
+	return &MaildirSender{cfg: cfg}
+}
+
+// deliveries counts the messages written by this process, to keep file names unique.
+var deliveries atomic.Int64
+
+// Send renders the draft as a MIME message and delivers it to <Path>/<Folder>/cur
+// with the "D" (draft) flag. Following the Maildir protocol, the message is first
+// written to tmp and then atomically renamed.
+func (s *MaildirSender) Send(draft models.DraftEmail) error {
+	if s.cfg.Path == "" {
+		return errors.New("maildir path is not configured")
+	}
+
+	root, err := homedir.Expand(s.cfg.Path)
+	if err != nil {
+		return err
+	}
+	folder := s.cfg.Folder
+	if folder == "" {
+		folder = DefaultFolder
+	}
+	dir := filepath.Join(root, folder)
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return fmt.Errorf("creating maildir folder %q: %w", dir, err)
+		}
+	}
+
+	now := time.Now()
+	msg, err := mimemsg.Build(draft, mimemsg.Options{
+		From:       s.cfg.From,
+		Date:       now,
+		IncludeBcc: true,
+	})
+	if err != nil {
+		return fmt.Errorf("building message: %w", err)
+	}
+
+	name, err := uniqueName(now)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(dir, "tmp", name)
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", tmpPath, err)
+	}
+	if _, err := f.Write(msg); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("writing %q: %w", tmpPath, err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("syncing %q: %w", tmpPath, err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("closing %q: %w", tmpPath, err)
+	}
+
+	curPath := filepath.Join(dir, "cur", name+":2,D")
+	if err := os.Rename(tmpPath, curPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("moving draft into %q: %w", curPath, err)
+	}
+
+	fmt.Printf("Draft saved to %s\n", curPath)
+	return nil
+}
+
+// uniqueName returns a Maildir file name of the form "<sec>.M<usec>P<pid>Q<n>.<host>".
+func uniqueName(now time.Time) (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("reading host name: %w", err)
+	}
+	// "/" and ":" are not allowed in the host part of a Maildir file name.
+	host = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(host)
+
+	return fmt.Sprintf("%d.M%dP%dQ%d.%s",
+		now.Unix(), now.Nanosecond()/1000, os.Getpid(), deliveries.Add(1), host), nil
+}
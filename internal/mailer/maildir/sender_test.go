@@ -0,0 +1,73 @@
+package maildir
+
+import (
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mailmate/internal/models"
+)
+
+func TestSend(t *testing.T) {
+	root := t.TempDir()
+	sender := NewSender(Config{Path: root, From: "jane@example.com"})
+
+	draft := models.DraftEmail{
+		To:       []mail.Address{{Address: "john@example.com"}},
+		Subject:  "Relance",
+		HTMLBody: "<p>Bonjour</p>",
+	}
+	for i := 0; i < 2; i++ {
+		if err := sender.Send(draft); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	for _, sub := range []string{"tmp", "new"} {
+		entries, err := os.ReadDir(filepath.Join(root, DefaultFolder, sub))
+		if err != nil {
+			t.Fatalf("reading %s: %v", sub, err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("%s contains %d files, want 0", sub, len(entries))
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, DefaultFolder, "cur"))
+	if err != nil {
+		t.Fatalf("reading cur: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("cur contains %d files, want 2", len(entries))
+	}
+	if entries[0].Name() == entries[1].Name() {
+		t.Errorf("file names are not unique: %s", entries[0].Name())
+	}
+
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ":2,D") {
+			t.Errorf("file %q does not carry the draft flag", e.Name())
+		}
+
+		f, err := os.Open(filepath.Join(root, DefaultFolder, "cur", e.Name()))
+		if err != nil {
+			t.Fatalf("opening draft: %v", err)
+		}
+		msg, err := mail.ReadMessage(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if got := msg.Header.Get("To"); got != "<john@example.com>" {
+			t.Errorf("To = %q", got)
+		}
+	}
+}
+
+func TestSendMissingPath(t *testing.T) {
+	if err := NewSender(Config{}).Send(models.DraftEmail{}); err == nil {
+		t.Errorf("Send() error = nil, want error")
+	}
+}
@@ -0,0 +1,384 @@
+package mimemsg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"mailmate/internal/calendar"
+	"mailmate/internal/models"
+)
+
+// Options holds the message-level settings that are not part of models.DraftEmail.
+type Options struct {
+	// From is the sender address written to the From header (e.g. "Jane <jane@example.com>").
+	From string
+	// Date is the value of the Date header. If zero, the current time is used.
+	Date time.Time
+	// IncludeBcc writes the Bcc header into the message.
+	// It must be false for messages handed to an SMTP server, and true for drafts
+	// that are stored and reviewed before sending.
+	IncludeBcc bool
+	// Unsent adds the "X-Unsent: 1" header so that mail clients such as Outlook
+	// open the message as an editable draft rather than as a received email.
+	Unsent bool
+}
+
+// Build renders the draft as an RFC 5322 message with MIME parts.
+//
+// The body is quoted-printable encoded. When the draft has a TextBody, it is sent as
+// multipart/alternative (plain text, then HTML), followed by the invitation of the
+// draft Event, if any. Inline attachments are grouped with the HTML part in a
+// multipart/related container, and regular attachments are placed next to the body
+// inside a multipart/mixed container:
+//
+//	multipart/mixed
+//	├── multipart/alternative
+//	│   ├── text/plain
+//	│   ├── multipart/related
+//	│   │   ├── text/html
+//	│   │   └── inline attachments
+//	│   └── text/calendar (invitation)
+//	└── attachments
+func Build(draft models.DraftEmail, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+
+	date := opts.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	// The sender of the backend is replaced by the sender of the draft, if any:
+	// the message is then sent on behalf of the draft sender.
+	var from, sender *mail.Address
+	if opts.From != "" {
+		var err error
+		from, err = mail.ParseAddress(opts.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from address %q: %w", opts.From, err)
+		}
+	}
+	if draft.From != nil {
+		if from != nil && !strings.EqualFold(from.Address, draft.From.Address) {
+			sender = from
+		}
+		from = draft.From
+	}
+	if from != nil {
+		writeHeader(&buf, "From", from.String())
+	}
+	if sender != nil {
+		writeHeader(&buf, "Sender", sender.String())
+	}
+	if len(draft.ReplyTo) > 0 {
+		writeHeader(&buf, "Reply-To", formatAddressList(draft.ReplyTo))
+	}
+
+	if len(draft.To) > 0 {
+		writeHeader(&buf, "To", formatAddressList(draft.To))
+	}
+	if len(draft.Cc) > 0 {
+		writeHeader(&buf, "Cc", formatAddressList(draft.Cc))
+	}
+	if opts.IncludeBcc && len(draft.Bcc) > 0 {
+		writeHeader(&buf, "Bcc", formatAddressList(draft.Bcc))
+	}
+
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", draft.Subject))
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID(from))
+	if opts.Unsent {
+		writeHeader(&buf, "X-Unsent", "1")
+	}
+
+	if err := writeOptionHeaders(&buf, draft, from); err != nil {
+		return nil, err
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	var invitation []byte
+	if draft.Event != nil {
+		event := *draft.Event
+		if event.Organizer == nil {
+			event.Organizer = from
+		}
+		var err error
+		if invitation, err = calendar.Encode(event, date); err != nil {
+			return nil, err
+		}
+	}
+
+	root, err := bodyEntity(draft, invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(root.header))
+	for k := range root.header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(&buf, k, root.header.Get(k))
+	}
+	buf.WriteString("\r\n")
+
+	if err := root.writeBody(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// priorities maps importance values to the X-Priority header still used by many clients.
+var priorities = map[models.Importance]string{
+	models.ImportanceHigh:   "1 (Highest)",
+	models.ImportanceNormal: "3 (Normal)",
+	models.ImportanceLow:    "5 (Lowest)",
+}
+
+// sensitivities maps sensitivity values to the Sensitivity header (RFC 2156).
+// Normal sensitivity has no header.
+var sensitivities = map[models.Sensitivity]string{
+	models.SensitivityPersonal:     "Personal",
+	models.SensitivityPrivate:      "Private",
+	models.SensitivityConfidential: "Company-Confidential",
+}
+
+// writeOptionHeaders writes the importance, sensitivity, receipt and custom headers of the draft.
+// Receipts are sent to from, which is therefore required to request them.
+func writeOptionHeaders(buf *bytes.Buffer, draft models.DraftEmail, from *mail.Address) error {
+	if draft.Importance != "" {
+		writeHeader(buf, "Importance", string(draft.Importance))
+		writeHeader(buf, "X-Priority", priorities[draft.Importance])
+	}
+	if v, ok := sensitivities[draft.Sensitivity]; ok {
+		writeHeader(buf, "Sensitivity", v)
+	}
+
+	if (draft.ReadReceipt || draft.DeliveryReceipt) && from == nil {
+		return errors.New("receipts require a from address")
+	}
+	if draft.ReadReceipt {
+		writeHeader(buf, "Disposition-Notification-To", from.String())
+	}
+	if draft.DeliveryReceipt {
+		writeHeader(buf, "Return-Receipt-To", from.String())
+	}
+
+	names := make([]string, 0, len(draft.Headers))
+	for name := range draft.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeHeader(buf, name, mime.QEncoding.Encode("utf-8", draft.Headers[name]))
+	}
+	return nil
+}
+
+// bodyEntity builds the MIME tree holding the body and attachments of the draft.
+// A meeting invitation is added both as an alternative of the body, for clients showing
+// accept and decline buttons, and as an .ics attachment for the others.
+func bodyEntity(draft models.DraftEmail, invitation []byte) (*entity, error) {
+	html, err := textEntity("text/html", draft.HTMLBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var inline, regular []*entity
+	for _, a := range draft.Attachments {
+		e, err := attachmentEntity(a)
+		if err != nil {
+			return nil, err
+		}
+		if a.Inline() {
+			inline = append(inline, e)
+		} else {
+			regular = append(regular, e)
+		}
+	}
+
+	if len(inline) > 0 {
+		html = multipartEntity("multipart/related", append([]*entity{html}, inline...)...)
+	}
+
+	var alternatives []*entity
+	if draft.TextBody != "" {
+		text, err := textEntity("text/plain", draft.TextBody)
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, text)
+	}
+	alternatives = append(alternatives, html)
+	if invitation != nil {
+		ics, err := textEntity("text/calendar", string(invitation))
+		if err != nil {
+			return nil, err
+		}
+		ics.header.Set("Content-Type", calendar.ContentType)
+		alternatives = append(alternatives, ics)
+
+		att, err := attachmentEntity(models.Attachment{Content: invitation, Name: calendar.FileName, ContentType: "application/ics"})
+		if err != nil {
+			return nil, err
+		}
+		regular = append(regular, att)
+	}
+
+	body := html
+	if len(alternatives) > 1 {
+		body = multipartEntity("multipart/alternative", alternatives...)
+	}
+
+	if len(regular) > 0 {
+		body = multipartEntity("multipart/mixed", append([]*entity{body}, regular...)...)
+	}
+	return body, nil
+}
+
+// entity is a MIME entity: either a leaf holding an encoded body, or a multipart container.
+type entity struct {
+	header   textproto.MIMEHeader
+	body     []byte
+	boundary string
+	parts    []*entity
+}
+
+// multipartEntity creates a multipart container of the given type.
+func multipartEntity(mediaType string, parts ...*entity) *entity {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	return &entity{
+		header: textproto.MIMEHeader{
+			"Content-Type": {mime.FormatMediaType(mediaType, map[string]string{"boundary": boundary})},
+		},
+		boundary: boundary,
+		parts:    parts,
+	}
+}
+
+// textEntity creates a quoted-printable encoded UTF-8 text part.
+func textEntity(mediaType, content string) (*entity, error) {
+	var body bytes.Buffer
+	qp := quotedprintable.NewWriter(&body)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return nil, fmt.Errorf("encoding %s body: %w", mediaType, err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("encoding %s body: %w", mediaType, err)
+	}
+	return &entity{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(mediaType, map[string]string{"charset": "utf-8"})},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		body: body.Bytes(),
+	}, nil
+}
+
+// attachmentEntity creates a base64 encoded part for the attachment.
+// Inline attachments get a Content-ID and an inline disposition.
+func attachmentEntity(a models.Attachment) (*entity, error) {
+	data, err := a.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading attachment %s: %w", a.FileName(), err)
+	}
+
+	name := a.FileName()
+	mediaType, params, err := mime.ParseMediaType(a.MediaType())
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	params["name"] = name
+
+	disposition := "attachment"
+	if a.Inline() {
+		disposition = "inline"
+	}
+
+	header := textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(mediaType, params)},
+		"Content-Disposition":       {mime.FormatMediaType(disposition, map[string]string{"filename": name})},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	if a.Inline() {
+		header.Set("Content-Id", "<"+a.ContentID+">")
+	}
+
+	var body bytes.Buffer
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		body.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	body.WriteString(encoded + "\r\n")
+
+	return &entity{header: header, body: body.Bytes()}, nil
+}
+
+// writeBody writes the body of the entity, recursively writing the parts of multipart containers.
+func (e *entity) writeBody(w io.Writer) error {
+	if e.parts == nil {
+		if _, err := w.Write(e.body); err != nil {
+			return fmt.Errorf("writing message: %w", err)
+		}
+		return nil
+	}
+
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(e.boundary); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	for _, p := range e.parts {
+		pw, err := mw.CreatePart(p.header)
+		if err != nil {
+			return fmt.Errorf("writing message: %w", err)
+		}
+		if err := p.writeBody(pw); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	return nil
+}
+
+// writeHeader writes a single header line terminated by CRLF.
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	fmt.Fprintf(buf, "%s: %s\r\n", name, value)
+}
+
+// formatAddressList joins addresses into a header value, encoding display names as needed.
+func formatAddressList(addrs []mail.Address) string {
+	parts := make([]string, len(addrs))
+	for i, a := range addrs {
+		parts[i] = a.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// messageID generates a unique Message-ID using the domain of the sender when available.
+func messageID(from *mail.Address) string {
+	domain := "mailmate.local"
+	if from != nil {
+		if at := strings.LastIndex(from.Address, "@"); at != -1 {
+			domain = from.Address[at+1:]
+		}
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
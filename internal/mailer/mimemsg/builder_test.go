@@ -0,0 +1,231 @@
+package mimemsg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mailmate/internal/models"
+)
+
+func TestBuildHTMLOnly(t *testing.T) {
+	raw, err := Build(models.DraftEmail{Subject: "Rapport", HTMLBody: "<p>Bonjour</p>"}, Options{})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if got := msg.Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html without a text alternative", got)
+	}
+}
+
+func TestBuildAttachments(t *testing.T) {
+	draft := models.DraftEmail{
+		To:       []mail.Address{{Address: "john@example.com"}},
+		Subject:  "Rapport",
+		HTMLBody: `<p>Bonjour</p><img src="cid:logo@mailmate">`,
+		TextBody: "Bonjour\n",
+		Attachments: []models.Attachment{
+			{Content: []byte("a,b\n1,2\n"), Name: "données.csv", ContentType: "text/csv"},
+			{Content: []byte("\x89PNG fake"), Name: "logo.png", ContentID: "logo@mailmate"},
+		},
+	}
+
+	raw, err := Build(draft, Options{From: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", mediaType)
+	}
+	mixed := multipart.NewReader(msg.Body, params["boundary"])
+
+	// multipart/alternative > multipart/related > text/html + inline image
+	alt := nextMultipart(t, mixed, "multipart/alternative")
+	if _, err := alt.NextPart(); err != nil {
+		t.Fatalf("reading text part: %v", err)
+	}
+	related := nextMultipart(t, alt, "multipart/related")
+	html, err := related.NextPart()
+	if err != nil || html.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("first related part = %q (%v), want text/html", html.Header.Get("Content-Type"), err)
+	}
+	img, err := related.NextPart()
+	if err != nil {
+		t.Fatalf("reading inline part: %v", err)
+	}
+	if got := img.Header.Get("Content-Id"); got != "<logo@mailmate>" {
+		t.Errorf("Content-Id = %q, want <logo@mailmate>", got)
+	}
+	if got := img.Header.Get("Content-Disposition"); got != "inline; filename=logo.png" {
+		t.Errorf("Content-Disposition = %q, want inline", got)
+	}
+	if got := img.Header.Get("Content-Type"); got != "image/png; name=logo.png" {
+		t.Errorf("Content-Type = %q, want image/png guessed from the name", got)
+	}
+
+	att, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("reading attachment part: %v", err)
+	}
+	if att.FileName() != "données.csv" {
+		t.Errorf("attachment filename = %q, want données.csv", att.FileName())
+	}
+	content, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, att))
+	if string(content) != "a,b\n1,2\n" {
+		t.Errorf("attachment content = %q", content)
+	}
+}
+
+// nextMultipart reads the next part of r, checks its media type and returns a reader over its parts.
+func nextMultipart(t *testing.T, r *multipart.Reader, want string) *multipart.Reader {
+	t.Helper()
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("reading %s part: %v", want, err)
+	}
+	mediaType, params, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+	if mediaType != want {
+		t.Fatalf("part = %q, want %s", mediaType, want)
+	}
+	return multipart.NewReader(p, params["boundary"])
+}
+
+func TestBuildHeaders(t *testing.T) {
+	draft := models.DraftEmail{
+		To:              []mail.Address{{Address: "john@example.com"}},
+		From:            &mail.Address{Name: "Support", Address: "support@example.com"},
+		ReplyTo:         []mail.Address{{Address: "tickets@example.com"}},
+		Subject:         "Rapport",
+		Importance:      models.ImportanceHigh,
+		Sensitivity:     models.SensitivityConfidential,
+		ReadReceipt:     true,
+		DeliveryReceipt: true,
+		Headers:         map[string]string{"X-Campaign": "relance-2026", "X-Projet": "Été"},
+		HTMLBody:        "<p>Bonjour</p>",
+	}
+
+	raw, err := Build(draft, Options{From: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+
+	want := map[string]string{
+		"From":                        `"Support" <support@example.com>`,
+		"Sender":                      "<jane@example.com>",
+		"Reply-To":                    "<tickets@example.com>",
+		"Importance":                  "high",
+		"X-Priority":                  "1 (Highest)",
+		"Sensitivity":                 "Company-Confidential",
+		"Disposition-Notification-To": `"Support" <support@example.com>`,
+		"Return-Receipt-To":           `"Support" <support@example.com>`,
+		"X-Campaign":                  "relance-2026",
+		"X-Projet":                    "=?utf-8?q?=C3=89t=C3=A9?=",
+	}
+	for name, value := range want {
+		if got := msg.Header.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if id := msg.Header.Get("Message-Id"); !bytes.HasSuffix([]byte(id), []byte("@example.com>")) {
+		t.Errorf("Message-Id = %q, want the domain of the sender", id)
+	}
+
+	// The draft sender replaces the backend sender without a Sender header when they match
+	raw, err = Build(draft, Options{From: "SUPPORT@example.com"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if bytes.Contains(raw, []byte("\r\nSender:")) {
+		t.Errorf("unexpected Sender header for the same address")
+	}
+
+	// Receipts are sent to the sender, which is then required
+	if _, err := Build(models.DraftEmail{ReadReceipt: true, HTMLBody: "<p>Bonjour</p>"}, Options{}); err == nil {
+		t.Errorf("Build() error = nil, want an error for a read receipt without a from address")
+	}
+}
+
+func TestBuildInvitation(t *testing.T) {
+	draft := models.DraftEmail{
+		To:       []mail.Address{{Address: "john@example.com"}},
+		Subject:  "Point projet",
+		HTMLBody: "<p>Invitation</p>",
+		TextBody: "Invitation\n",
+		Event: &models.Event{
+			UID:       "42@mailmate",
+			Summary:   "Point projet",
+			Start:     time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+			End:       time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC),
+			Attendees: []mail.Address{{Address: "john@example.com"}},
+		},
+	}
+
+	raw, err := Build(draft, Options{From: "Jane <jane@example.com>"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", mediaType)
+	}
+	mixed := multipart.NewReader(msg.Body, params["boundary"])
+
+	// multipart/alternative > text/plain, text/html, text/calendar
+	alt := nextMultipart(t, mixed, "multipart/alternative")
+	var types []string
+	var ics []byte
+	for {
+		p, err := alt.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading alternative part: %v", err)
+		}
+		types = append(types, p.Header.Get("Content-Type"))
+		if strings.HasPrefix(p.Header.Get("Content-Type"), "text/calendar") {
+			ics, _ = io.ReadAll(p)
+		}
+	}
+	want := []string{"text/plain; charset=utf-8", "text/html; charset=utf-8", "text/calendar; method=REQUEST; charset=utf-8"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("alternative parts = %v, want %v", types, want)
+	}
+	// The organizer defaults to the sender
+	if !bytes.Contains(ics, []byte(`ORGANIZER;CN="Jane":mailto:jane@example.com`)) {
+		t.Errorf("invitation = %s, want the sender as organizer", ics)
+	}
+
+	att, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("reading attachment: %v", err)
+	}
+	if got := att.Header.Get("Content-Disposition"); got != "attachment; filename=invitation.ics" {
+		t.Errorf("Content-Disposition = %q, want the invitation attachment", got)
+	}
+}
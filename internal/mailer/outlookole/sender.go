@@ -2,6 +2,13 @@ package outlookole
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"mailmate/internal/address"
+	"mailmate/internal/calendar"
 	"mailmate/internal/mailer"
 	"mailmate/internal/models"
 
@@ -9,6 +16,13 @@ import (
 	"github.com/go-ole/go-ole/oleutil"
 )
 
+// init registers the Outlook backend.
+func init() {
+	mailer.Register("outlook", func(func(v any) error) (mailer.EmailSender, error) {
+		return NewSender(), nil
+	})
+}
+
 // OutlookSender implements mailer.EmailSender using Outlook OLE automation.
 type OutlookSender struct{}
 
@@ -51,18 +65,19 @@ func (s *OutlookSender) Send(draft models.DraftEmail) error {
 	defer mailItem.Release()
 
 	// Set email properties
-	if draft.To != "" {
-		if _, err := oleutil.PutProperty(mailItem, "To", draft.To); err != nil {
+	if len(draft.To) > 0 {
+		// Outlook expects recipients separated by semicolons.
+		if _, err := oleutil.PutProperty(mailItem, "To", address.Join(draft.To, "; ")); err != nil {
 			return fmt.Errorf("failed to set mail property %q: %w", "To", err)
 		}
 	}
-	if draft.Cc != "" {
-		if _, err := oleutil.PutProperty(mailItem, "CC", draft.Cc); err != nil {
+	if len(draft.Cc) > 0 {
+		if _, err := oleutil.PutProperty(mailItem, "CC", address.Join(draft.Cc, "; ")); err != nil {
 			return fmt.Errorf("failed to set mail property %q: %w", "CC", err)
 		}
 	}
-	if draft.Bcc != "" {
-		if _, err := oleutil.PutProperty(mailItem, "BCC", draft.Bcc); err != nil {
+	if len(draft.Bcc) > 0 {
+		if _, err := oleutil.PutProperty(mailItem, "BCC", address.Join(draft.Bcc, "; ")); err != nil {
 			return fmt.Errorf("failed to set mail property %q: %w", "BCC", err)
 		}
 	}
@@ -74,8 +89,17 @@ func (s *OutlookSender) Send(draft models.DraftEmail) error {
 		return fmt.Errorf("failed to set mail property %q: %w", "HTMLBody", err)
 	}
 
-	// Add attachments
-	if len(draft.Attachments) > 0 {
+	if err := setOptions(mailItem, draft); err != nil {
+		return err
+	}
+
+	// Add attachments. A meeting invitation is attached as an .ics file:
+	// the draft stays a mail item, not a meeting request.
+	attachmentList, err := calendar.WithInvitation(draft, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(attachmentList) > 0 {
 		attachments := oleutil.MustGetProperty(mailItem, "Attachments").ToIDispatch()
 		// No need to release attachments IDispatch as it is managed by the parent object?
 		// Actually it's better to be safe, but MustGetProperty might not return an owned reference in the same way.
@@ -83,14 +107,16 @@ func (s *OutlookSender) Send(draft models.DraftEmail) error {
 		// However, Go-OLE docs/examples often show direct method calls on the item for simple properties,
 		// but Attachments is a collection.
 
-		for _, path := range draft.Attachments {
-			// Attachments.Add(Source, Type, Position, DisplayName)
-			// Source is required. Others are optional.
-			_, err := attachments.CallMethod("Add", path)
-			if err != nil {
-				// We log/return error but maybe we should continue adding others?
-				// For now let's be strict.
-				return fmt.Errorf("failed to add attachment %s: %w", path, err)
+		// In-memory attachments are written to a temporary directory, kept until the draft is saved.
+		tmpDir, err := os.MkdirTemp("", "mailmate-")
+		if err != nil {
+			return fmt.Errorf("creating temporary directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tmpDir) }()
+
+		for i, a := range attachmentList {
+			if err := addAttachment(attachments, a, tmpDir, i); err != nil {
+				return err
 			}
 		}
 	}
@@ -107,3 +133,136 @@ func (s *OutlookSender) Send(draft models.DraftEmail) error {
 
 	return nil
 }
+
+// Outlook constants used for the message options.
+const (
+	// propInternetHeaders is the MAPI named property namespace (PS_INTERNET_HEADERS) of custom internet headers.
+	propInternetHeaders = "http://schemas.microsoft.com/mapi/string/{00020386-0000-0000-C000-000000000046}/"
+)
+
+// importances maps importance values to OlImportance values.
+var importances = map[models.Importance]int{
+	models.ImportanceLow:    0,
+	models.ImportanceNormal: 1,
+	models.ImportanceHigh:   2,
+}
+
+// sensitivities maps sensitivity values to OlSensitivity values.
+var sensitivities = map[models.Sensitivity]int{
+	models.SensitivityNormal:       0,
+	models.SensitivityPersonal:     1,
+	models.SensitivityPrivate:      2,
+	models.SensitivityConfidential: 3,
+}
+
+// setOptions sets the sender, reply-to addresses, importance, sensitivity, receipts
+// and custom headers of the mail item.
+func setOptions(mailItem *ole.IDispatch, draft models.DraftEmail) error {
+	properties := map[string]any{}
+	if draft.From != nil {
+		// The draft is sent on behalf of this address, which requires the "Send on behalf" permission.
+		properties["SentOnBehalfOfName"] = draft.From.Address
+	}
+	if v, ok := importances[draft.Importance]; ok {
+		properties["Importance"] = v
+	}
+	if v, ok := sensitivities[draft.Sensitivity]; ok {
+		properties["Sensitivity"] = v
+	}
+	if draft.ReadReceipt {
+		properties["ReadReceiptRequested"] = true
+	}
+	if draft.DeliveryReceipt {
+		properties["OriginatorDeliveryReportRequested"] = true
+	}
+	for name, value := range properties {
+		if _, err := oleutil.PutProperty(mailItem, name, value); err != nil {
+			return fmt.Errorf("failed to set mail property %q: %w", name, err)
+		}
+	}
+
+	if len(draft.ReplyTo) > 0 {
+		replyRecipients, err := oleutil.GetProperty(mailItem, "ReplyRecipients")
+		if err != nil {
+			return fmt.Errorf("failed to set mail property %q: %w", "ReplyRecipients", err)
+		}
+		recipients := replyRecipients.ToIDispatch()
+		defer recipients.Release()
+
+		for _, a := range draft.ReplyTo {
+			if _, err := recipients.CallMethod("Add", address.Format(a)); err != nil {
+				return fmt.Errorf("failed to add reply-to address %s: %w", a.Address, err)
+			}
+		}
+	}
+
+	if len(draft.Headers) > 0 {
+		accessor, err := oleutil.GetProperty(mailItem, "PropertyAccessor")
+		if err != nil {
+			return fmt.Errorf("failed to set custom headers: %w", err)
+		}
+		propertyAccessor := accessor.ToIDispatch()
+		defer propertyAccessor.Release()
+
+		for name, value := range draft.Headers {
+			if _, err := propertyAccessor.CallMethod("SetProperty", propInternetHeaders+name, value); err != nil {
+				return fmt.Errorf("failed to set header %q: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Outlook constants used when adding attachments.
+const (
+	// olByValue stores a copy of the file in the item.
+	olByValue = 1
+	// propAttachContentID is the MAPI property PR_ATTACH_CONTENT_ID, referenced by "cid:" URLs.
+	propAttachContentID = "http://schemas.microsoft.com/mapi/proptag/0x3712001F"
+)
+
+// addAttachment adds a to the Attachments collection with
+// Attachments.Add(Source, Type, Position, DisplayName).
+// In-memory content is first written to a file under tmpDir, and inline attachments
+// get their content ID so that the HTML body can reference them.
+func addAttachment(attachments *ole.IDispatch, a models.Attachment, tmpDir string, index int) error {
+	name := a.FileName()
+	source := a.Path
+	if a.Content != nil {
+		dir := filepath.Join(tmpDir, strconv.Itoa(index))
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			return fmt.Errorf("writing attachment %s: %w", name, err)
+		}
+		source = filepath.Join(dir, filepath.Base(name))
+		if err := os.WriteFile(source, a.Content, 0o600); err != nil {
+			return fmt.Errorf("writing attachment %s: %w", name, err)
+		}
+	}
+
+	// Position 0 hides inline attachments from the attachment bar.
+	position := 1
+	if a.Inline() {
+		position = 0
+	}
+
+	result, err := attachments.CallMethod("Add", source, olByValue, position, name)
+	if err != nil {
+		return fmt.Errorf("failed to add attachment %s: %w", name, err)
+	}
+	attachment := result.ToIDispatch()
+	defer attachment.Release()
+
+	if a.Inline() {
+		accessor, err := oleutil.GetProperty(attachment, "PropertyAccessor")
+		if err != nil {
+			return fmt.Errorf("failed to set content id of attachment %s: %w", name, err)
+		}
+		propertyAccessor := accessor.ToIDispatch()
+		defer propertyAccessor.Release()
+
+		if _, err := propertyAccessor.CallMethod("SetProperty", propAttachContentID, a.ContentID); err != nil {
+			return fmt.Errorf("failed to set content id of attachment %s: %w", name, err)
+		}
+	}
+	return nil
+}
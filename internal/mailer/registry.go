@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates an EmailSender from backend-specific options.
+// decode unmarshals the backend's section of the configuration file into the value
+// it is given, and leaves that value untouched when the section is absent.
+type Factory func(decode func(v any) error) (EmailSender, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a backend available under the given name.
+// It is meant to be called from the init function of the backend package and
+// panics if the name is registered twice.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, dup := registry[name]; dup {
+		panic(fmt.Errorf("mailer: backend %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// NewSender creates the EmailSender registered under name.
+func NewSender(name string, decode func(v any) error) (EmailSender, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q (available: %v)", name, Backends())
+	}
+	if decode == nil {
+		decode = func(any) error { return nil }
+	}
+
+	sender, err := factory(decode)
+	if err != nil {
+		return nil, fmt.Errorf("configuring backend %q: %w", name, err)
+	}
+	return sender, nil
+}
+
+// Backends returns the sorted names of all registered backends.
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
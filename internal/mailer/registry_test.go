@@ -0,0 +1,70 @@
+package mailer
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"mailmate/internal/models"
+)
+
+// fakeSender is an EmailSender recording its options.
+type fakeSender struct {
+	Host string `yaml:"host"`
+}
+
+// Send implements EmailSender.
+func (*fakeSender) Send(models.DraftEmail) error {
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("test-fake", func(decode func(v any) error) (EmailSender, error) {
+		s := &fakeSender{Host: "localhost"}
+		if err := decode(s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+	Register("test-broken", func(func(v any) error) (EmailSender, error) {
+		return nil, errors.New("missing host")
+	})
+
+	if names := Backends(); !slices.Contains(names, "test-fake") || !slices.IsSorted(names) {
+		t.Errorf("Backends() = %v, want the sorted names including test-fake", names)
+	}
+
+	// Without options, the defaults of the backend are kept
+	sender, err := NewSender("test-fake", nil)
+	if err != nil {
+		t.Fatalf("NewSender() error = %v", err)
+	}
+	if got := sender.(*fakeSender).Host; got != "localhost" {
+		t.Errorf("Host = %q, want localhost", got)
+	}
+
+	sender, err = NewSender("test-fake", func(v any) error {
+		v.(*fakeSender).Host = "smtp.example.com"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewSender() error = %v", err)
+	}
+	if got := sender.(*fakeSender).Host; got != "smtp.example.com" {
+		t.Errorf("Host = %q, want the decoded option", got)
+	}
+
+	if _, err := NewSender("test-broken", nil); err == nil {
+		t.Errorf("NewSender() error = nil, want the factory error")
+	}
+	if _, err := NewSender("test-missing", nil); err == nil {
+		t.Errorf("NewSender() error = nil, want an error for an unknown backend")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() did not panic for a duplicate name")
+		}
+	}()
+	Register("test-fake", nil)
+}
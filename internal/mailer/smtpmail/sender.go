@@ -0,0 +1,260 @@
+package smtpmail
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mailmate/internal/mailer"
+	"mailmate/internal/mailer/mimemsg"
+	"mailmate/internal/models"
+)
+
+// Security modes supported by the SMTP sender.
+const (
+	// SecurityStartTLS connects in plain text and upgrades the connection with STARTTLS.
+	SecurityStartTLS = "starttls"
+	// SecurityTLS connects over implicit TLS (usually port 465).
+	SecurityTLS = "tls"
+	// SecurityNone never encrypts the connection. Only use it with local relays.
+	SecurityNone = "none"
+)
+
+// Authentication mechanisms supported by the SMTP sender.
+const (
+	// AuthPlain uses the SASL PLAIN mechanism.
+	AuthPlain = "plain"
+	// AuthLogin uses the legacy LOGIN mechanism still required by some servers.
+	AuthLogin = "login"
+)
+
+// Config holds the settings required to deliver a message through an SMTP server.
+type Config struct {
+	// Host is the SMTP server host name.
+	Host string `yaml:"host"`
+	// Port is the SMTP server port. Defaults to 587, or 465 with SecurityTLS.
+	Port int `yaml:"port"`
+	// Username is the login used for authentication. Authentication is skipped if empty.
+	Username string `yaml:"username"`
+	// Password is the secret used for authentication.
+	// If empty, the MAILMATE_SMTP_PASSWORD environment variable is used.
+	Password string `yaml:"password"`
+	// From is the sender address used for the envelope and the From header.
+	From string `yaml:"from"`
+	// Security is one of SecurityStartTLS (default), SecurityTLS or SecurityNone.
+	Security string `yaml:"security"`
+	// Auth is one of AuthPlain (default) or AuthLogin.
+	Auth string `yaml:"auth"`
+	// InsecureSkipVerify disables TLS certificate verification. Only use it for testing.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// Timeout bounds the connection establishment. Defaults to 30 seconds.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// init registers the SMTP backend.
+func init() {
+	mailer.Register("smtp", func(decode func(v any) error) (mailer.EmailSender, error) {
+		var cfg Config
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Password == "" {
+			cfg.Password = os.Getenv("MAILMATE_SMTP_PASSWORD")
+		}
+		return NewSender(cfg), nil
+	})
+}
+
+// SMTPSender implements mailer.EmailSender by delivering messages through an SMTP server.
+type SMTPSender struct {
+	cfg Config
+}
+
+// NewSender creates a new instance of SMTPSender.
+func NewSender(cfg Config) mailer.EmailSender {
+	return &SMTPSender{cfg: cfg}
+}
+
+// Send builds a MIME message from the draft and delivers it to every To, Cc and Bcc recipient.
+// Unlike the Outlook sender, the email is actually sent: there is no draft to review.
+func (s *SMTPSender) Send(draft models.DraftEmail) error {
+	if s.cfg.Host == "" {
+		return errors.New("smtp host is not configured")
+	}
+	if s.cfg.From == "" {
+		return errors.New("smtp from address is not configured")
+	}
+
+	from, err := mail.ParseAddress(s.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address %q: %w", s.cfg.From, err)
+	}
+
+	var rcpts []string
+	for _, list := range [][]mail.Address{draft.To, draft.Cc, draft.Bcc} {
+		for _, a := range list {
+			rcpts = append(rcpts, a.Address)
+		}
+	}
+	if len(rcpts) == 0 {
+		return errors.New("no recipients specified")
+	}
+
+	msg, err := mimemsg.Build(draft, mimemsg.Options{From: s.cfg.From})
+	if err != nil {
+		return fmt.Errorf("building message: %w", err)
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	if s.cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support authentication")
+		}
+		if err := client.Auth(s.auth()); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+	for _, rcpt := range rcpts {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s failed: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial opens a connection to the server and negotiates TLS according to the configured security mode.
+func (s *SMTPSender) dial() (*smtp.Client, error) {
+	security := strings.ToLower(s.cfg.Security)
+	if security == "" {
+		security = SecurityStartTLS
+	}
+
+	port := s.cfg.Port
+	if port == 0 {
+		port = 587
+		if security == SecurityTLS {
+			port = 465
+		}
+	}
+
+	timeout := s.cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{
+		ServerName:         s.cfg.Host,
+		InsecureSkipVerify: s.cfg.InsecureSkipVerify, //nolint:gosec // opt-in for testing only
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	switch security {
+	case SecurityTLS:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	case SecurityStartTLS, SecurityNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown smtp security mode: %s", s.cfg.Security)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to smtp server %s: %w", addr, err)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("smtp handshake with %s failed: %w", addr, err)
+	}
+
+	if security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			_ = client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("smtp STARTTLS failed: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// auth returns the smtp.Auth matching the configured mechanism.
+func (s *SMTPSender) auth() smtp.Auth {
+	if strings.EqualFold(s.cfg.Auth, AuthLogin) {
+		return &loginAuth{username: s.cfg.Username, password: s.cfg.Password, host: s.cfg.Host}
+	}
+	return smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+}
+
+// loginAuth implements the LOGIN authentication mechanism, which net/smtp does not provide.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+// Start begins the LOGIN exchange. Like smtp.PlainAuth, it refuses to send
+// credentials over an unencrypted connection unless the server is local.
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+// Next answers the server's username and password challenges.
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	challenge := strings.ToLower(strings.TrimSpace(string(fromServer)))
+	switch {
+	case strings.HasPrefix(challenge, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(challenge, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge: %q", fromServer)
+	}
+}
+
+// isLocalhost reports whether name designates the local machine.
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
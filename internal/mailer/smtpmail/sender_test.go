@@ -0,0 +1,241 @@
+package smtpmail
+
+import (
+	"encoding/base64"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"mailmate/internal/models"
+)
+
+// stubServer is a minimal in-process SMTP server recording what it receives.
+type stubServer struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	creds []string
+	from  string
+	rcpts []string
+	data  string
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &stubServer{listener: l}
+	t.Cleanup(func() {
+		if err := l.Close(); err != nil {
+			t.Logf("failed to close listener: %v", err)
+		}
+	})
+	go s.serve()
+	return s
+}
+
+func (s *stubServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *stubServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 stub ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250-stub")
+			_ = tp.PrintfLine("250 AUTH PLAIN LOGIN")
+		case "AUTH":
+			s.handleAuth(tp, line)
+		case "MAIL":
+			s.mu.Lock()
+			s.from = line
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, line)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 Go ahead")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data = strings.Join(lines, "\n")
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 Queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func (s *stubServer) handleAuth(tp *textproto.Conn, line string) {
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && strings.EqualFold(fields[1], "LOGIN") {
+		for _, prompt := range []string{"Username:", "Password:"} {
+			_ = tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+			resp, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			decoded, _ := base64.StdEncoding.DecodeString(resp)
+			s.mu.Lock()
+			s.creds = append(s.creds, string(decoded))
+			s.mu.Unlock()
+		}
+	} else if len(fields) == 3 {
+		decoded, _ := base64.StdEncoding.DecodeString(fields[2])
+		s.mu.Lock()
+		s.creds = strings.Split(string(decoded), "\x00")
+		s.mu.Unlock()
+	}
+	_ = tp.PrintfLine("235 Authentication successful")
+}
+
+func TestSend(t *testing.T) {
+	attachment := filepath.Join(t.TempDir(), "report.pdf")
+	if err := os.WriteFile(attachment, []byte("%PDF-1.4 fake"), 0o600); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		auth      string
+		wantCreds []string
+	}{
+		{
+			name:      "plain auth",
+			auth:      AuthPlain,
+			wantCreds: []string{"", "jane", "secret"},
+		},
+		{
+			name:      "login auth",
+			auth:      AuthLogin,
+			wantCreds: []string{"jane", "secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newStubServer(t)
+			sender := NewSender(Config{
+				Host:     "127.0.0.1",
+				Port:     srv.port(),
+				Username: "jane",
+				Password: "secret",
+				From:     "Jane <jane@example.com>",
+				Security: SecurityNone,
+				Auth:     tt.auth,
+			})
+
+			draft := models.DraftEmail{
+				To:          []mail.Address{{Address: "john@example.com"}, {Name: "Marie", Address: "marie@example.com"}},
+				Cc:          []mail.Address{{Address: "boss@example.com"}},
+				Bcc:         []mail.Address{{Address: "archive@example.com"}},
+				Subject:     "Relance facture 42 – été",
+				HTMLBody:    "<p>Bonjour</p>",
+				Attachments: []models.Attachment{{Path: attachment}},
+			}
+			if err := sender.Send(draft); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+
+			if strings.Join(srv.creds, "|") != strings.Join(tt.wantCreds, "|") {
+				t.Errorf("credentials = %q, want %q", srv.creds, tt.wantCreds)
+			}
+			if srv.from != "MAIL FROM:<jane@example.com>" && !strings.HasPrefix(srv.from, "MAIL FROM:<jane@example.com> ") {
+				t.Errorf("MAIL FROM = %q", srv.from)
+			}
+			wantRcpts := []string{"john@example.com", "marie@example.com", "boss@example.com", "archive@example.com"}
+			if len(srv.rcpts) != len(wantRcpts) {
+				t.Fatalf("got %d recipients, want %d: %v", len(srv.rcpts), len(wantRcpts), srv.rcpts)
+			}
+			for i, want := range wantRcpts {
+				if srv.rcpts[i] != "RCPT TO:<"+want+">" {
+					t.Errorf("recipient %d = %q, want %q", i, srv.rcpts[i], want)
+				}
+			}
+
+			for _, want := range []string{
+				"To: <john@example.com>, \"Marie\" <marie@example.com>",
+				"Cc: <boss@example.com>",
+				"Subject: =?utf-8?q?",
+				"Content-Type: multipart/mixed;",
+				"Content-Disposition: attachment; filename=report.pdf",
+			} {
+				if !strings.Contains(srv.data, want) {
+					t.Errorf("message does not contain %q:\n%s", want, srv.data)
+				}
+			}
+			if strings.Contains(srv.data, "Bcc:") {
+				t.Errorf("message must not contain a Bcc header:\n%s", srv.data)
+			}
+		})
+	}
+}
+
+func TestSendValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		draft models.DraftEmail
+	}{
+		{
+			name:  "missing host",
+			cfg:   Config{From: "jane@example.com"},
+			draft: models.DraftEmail{To: []mail.Address{{Address: "john@example.com"}}},
+		},
+		{
+			name:  "missing from",
+			cfg:   Config{Host: "127.0.0.1"},
+			draft: models.DraftEmail{To: []mail.Address{{Address: "john@example.com"}}},
+		},
+		{
+			name:  "no recipients",
+			cfg:   Config{Host: "127.0.0.1", From: "jane@example.com"},
+			draft: models.DraftEmail{},
+		},
+		{
+			name:  "invalid from",
+			cfg:   Config{Host: "127.0.0.1", From: "not an address"},
+			draft: models.DraftEmail{To: []mail.Address{{Address: "john@example.com"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewSender(tt.cfg).Send(tt.draft); err == nil {
+				t.Errorf("Send() error = nil, want error")
+			}
+		})
+	}
+}
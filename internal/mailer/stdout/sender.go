@@ -0,0 +1,323 @@
+package stdout
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/mail"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"mailmate/internal/address"
+	"mailmate/internal/calendar"
+	"mailmate/internal/mailer"
+	"mailmate/internal/models"
+)
+
+// Output formats supported by the stdout backend.
+const (
+	// FormatText prints a human readable summary.
+	FormatText = "text"
+	// FormatJSON prints a single JSON object, for scripts.
+	FormatJSON = "json"
+)
+
+// Body modes supported by the stdout backend.
+const (
+	// BodyInline prints the HTML body itself.
+	BodyInline = "inline"
+	// BodyFile writes the HTML body to a temporary file and prints its path.
+	BodyFile = "file"
+	// BodyNone omits the body.
+	BodyNone = "none"
+)
+
+// Config holds the settings of the stdout backend.
+type Config struct {
+	// Format is one of FormatText (default) or FormatJSON.
+	Format string `yaml:"format"`
+	// Body is one of BodyInline (default), BodyFile or BodyNone.
+	Body string `yaml:"body"`
+}
+
+// init registers the stdout backend.
+func init() {
+	mailer.Register("stdout", func(decode func(v any) error) (mailer.EmailSender, error) {
+		var cfg Config
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		return NewSender(cfg), nil
+	})
+}
+
+// StdoutSender implements mailer.EmailSender by printing the draft instead of creating it.
+// It is a dry run: no mailbox is touched.
+type StdoutSender struct {
+	cfg Config
+	out io.Writer
+}
+
+// NewSender creates a new instance of StdoutSender.
+func NewSender(cfg Config) mailer.EmailSender {
+	return &StdoutSender{cfg: cfg, out: os.Stdout}
+}
+
+// attachmentInfo describes an attachment in the output.
+type attachmentInfo struct {
+	Path        string `json:"path,omitempty"`
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+// recipient describes an email address in the output.
+type recipient struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+}
+
+// eventInfo describes a meeting invitation in the output.
+type eventInfo struct {
+	UID               string      `json:"uid"`
+	Summary           string      `json:"summary"`
+	Start             time.Time   `json:"start"`
+	End               time.Time   `json:"end"`
+	AllDay            bool        `json:"all_day,omitempty"`
+	Location          string      `json:"location,omitempty"`
+	Organizer         *recipient  `json:"organizer,omitempty"`
+	Attendees         []recipient `json:"attendees"`
+	OptionalAttendees []recipient `json:"optional_attendees,omitempty"`
+	// when is the human readable date of the event, for the text output.
+	when string
+}
+
+// report is the printed representation of a draft.
+type report struct {
+	From            *recipient        `json:"from,omitempty"`
+	To              []recipient       `json:"to"`
+	Cc              []recipient       `json:"cc"`
+	Bcc             []recipient       `json:"bcc"`
+	ReplyTo         []recipient       `json:"reply_to,omitempty"`
+	Subject         string            `json:"subject"`
+	Importance      string            `json:"importance,omitempty"`
+	Sensitivity     string            `json:"sensitivity,omitempty"`
+	ReadReceipt     bool              `json:"read_receipt,omitempty"`
+	DeliveryReceipt bool              `json:"delivery_receipt,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Event           *eventInfo        `json:"event,omitempty"`
+	Attachments     []attachmentInfo  `json:"attachments"`
+	HTMLBody        string            `json:"html_body,omitempty"`
+	TextBody        string            `json:"text_body,omitempty"`
+	HTMLFile        string            `json:"html_file,omitempty"`
+}
+
+// Send prints the draft in the configured format.
+func (s *StdoutSender) Send(draft models.DraftEmail) error {
+	r := report{
+		To:              recipients(draft.To),
+		Cc:              recipients(draft.Cc),
+		Bcc:             recipients(draft.Bcc),
+		ReplyTo:         recipients(draft.ReplyTo),
+		Subject:         draft.Subject,
+		Importance:      string(draft.Importance),
+		Sensitivity:     string(draft.Sensitivity),
+		ReadReceipt:     draft.ReadReceipt,
+		DeliveryReceipt: draft.DeliveryReceipt,
+		Headers:         draft.Headers,
+		Attachments:     []attachmentInfo{},
+	}
+	if draft.From != nil {
+		r.From = &recipients([]mail.Address{*draft.From})[0]
+	}
+	if e := draft.Event; e != nil {
+		r.Event = &eventInfo{
+			UID:               e.UID,
+			Summary:           e.Summary,
+			Start:             e.Start,
+			End:               e.End,
+			AllDay:            e.AllDay,
+			Location:          e.Location,
+			Attendees:         recipients(e.Attendees),
+			OptionalAttendees: recipients(e.OptionalAttendees),
+			when:              calendar.Describe(*e),
+		}
+		if e.Organizer != nil {
+			r.Event.Organizer = &recipients([]mail.Address{*e.Organizer})[0]
+		}
+	}
+
+	for _, a := range draft.Attachments {
+		size, err := a.Size()
+		if err != nil {
+			return fmt.Errorf("reading attachment %s: %w", a.FileName(), err)
+		}
+		info := attachmentInfo{
+			Name:        a.FileName(),
+			Size:        size,
+			ContentType: a.MediaType(),
+			ContentID:   a.ContentID,
+		}
+		if a.Content == nil {
+			info.Path = a.Path
+		}
+		r.Attachments = append(r.Attachments, info)
+	}
+
+	switch strings.ToLower(s.cfg.Body) {
+	case "", BodyInline:
+		r.HTMLBody = draft.HTMLBody
+		r.TextBody = draft.TextBody
+	case BodyFile:
+		path, err := writeBodyFile(draft.HTMLBody)
+		if err != nil {
+			return err
+		}
+		r.HTMLFile = path
+	case BodyNone:
+	default:
+		return fmt.Errorf("unknown body mode: %s", s.cfg.Body)
+	}
+
+	switch strings.ToLower(s.cfg.Format) {
+	case "", FormatText:
+		return s.printText(r)
+	case FormatJSON:
+		enc := json.NewEncoder(s.out)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("encoding draft: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s", s.cfg.Format)
+	}
+}
+
+// printText writes the human readable summary of the draft.
+func (s *StdoutSender) printText(r report) error {
+	var b strings.Builder
+	if r.From != nil {
+		fmt.Fprintf(&b, "From:    %s\n", joinRecipients([]recipient{*r.From}))
+	}
+	fmt.Fprintf(&b, "To:      %s\n", joinRecipients(r.To))
+	if len(r.Cc) > 0 {
+		fmt.Fprintf(&b, "Cc:      %s\n", joinRecipients(r.Cc))
+	}
+	if len(r.Bcc) > 0 {
+		fmt.Fprintf(&b, "Bcc:     %s\n", joinRecipients(r.Bcc))
+	}
+	if len(r.ReplyTo) > 0 {
+		fmt.Fprintf(&b, "Reply-To: %s\n", joinRecipients(r.ReplyTo))
+	}
+	fmt.Fprintf(&b, "Subject: %s\n", r.Subject)
+
+	var options []string
+	if r.Importance != "" {
+		options = append(options, "importance "+r.Importance)
+	}
+	if r.Sensitivity != "" {
+		options = append(options, "sensitivity "+r.Sensitivity)
+	}
+	if r.ReadReceipt {
+		options = append(options, "read receipt")
+	}
+	if r.DeliveryReceipt {
+		options = append(options, "delivery receipt")
+	}
+	if len(options) > 0 {
+		fmt.Fprintf(&b, "Options: %s\n", strings.Join(options, ", "))
+	}
+	if e := r.Event; e != nil {
+		fmt.Fprintf(&b, "Event:   %s, %s\n", e.Summary, e.when)
+		if e.Location != "" {
+			fmt.Fprintf(&b, "         %s\n", e.Location)
+		}
+	}
+	if len(r.Headers) > 0 {
+		b.WriteString("Headers:\n")
+		for _, name := range slices.Sorted(maps.Keys(r.Headers)) {
+			fmt.Fprintf(&b, "  %s: %s\n", name, r.Headers[name])
+		}
+	}
+
+	if len(r.Attachments) > 0 {
+		b.WriteString("Attachments:\n")
+		for _, a := range r.Attachments {
+			line := fmt.Sprintf("  - %s (%s, %s)", a.Name, a.ContentType, formatSize(a.Size))
+			if a.ContentID != "" {
+				line += " inline cid:" + a.ContentID
+			}
+			if a.Path != "" {
+				line += " " + a.Path
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	switch {
+	case r.HTMLFile != "":
+		fmt.Fprintf(&b, "Body:    %s\n", r.HTMLFile)
+	case r.HTMLBody != "":
+		fmt.Fprintf(&b, "\n%s\n", r.HTMLBody)
+	}
+
+	if _, err := io.WriteString(s.out, b.String()); err != nil {
+		return fmt.Errorf("writing draft: %w", err)
+	}
+	return nil
+}
+
+// recipients converts addresses to their output representation.
+// It never returns nil so that JSON output always contains arrays.
+func recipients(list []mail.Address) []recipient {
+	out := make([]recipient, 0, len(list))
+	for _, a := range list {
+		out = append(out, recipient{Name: a.Name, Address: a.Address})
+	}
+	return out
+}
+
+// joinRecipients formats recipients for the text output.
+func joinRecipients(list []recipient) string {
+	addrs := make([]mail.Address, len(list))
+	for i, r := range list {
+		addrs[i] = mail.Address{Name: r.Name, Address: r.Address}
+	}
+	return address.Join(addrs, ", ")
+}
+
+// writeBodyFile stores the HTML body in a temporary file and returns its path.
+func writeBodyFile(body string) (string, error) {
+	f, err := os.CreateTemp("", "mailmate-*.html")
+	if err != nil {
+		return "", fmt.Errorf("creating body file: %w", err)
+	}
+	if _, err := f.WriteString(body); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("writing body file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("closing body file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// formatSize formats a byte count with a binary unit (e.g. "12.3 KiB").
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
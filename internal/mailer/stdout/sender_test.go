@@ -0,0 +1,94 @@
+package stdout
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mailmate/internal/models"
+)
+
+func TestSend(t *testing.T) {
+	attachment := filepath.Join(t.TempDir(), "report.pdf")
+	if err := os.WriteFile(attachment, bytes.Repeat([]byte("x"), 2048), 0o600); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+	draft := models.DraftEmail{
+		To:          []mail.Address{{Address: "john@example.com"}},
+		Cc:          []mail.Address{{Address: "boss@example.com"}},
+		Subject:     "Rapport",
+		HTMLBody:    "<p>Bonjour</p>",
+		Attachments: []models.Attachment{{Path: attachment}},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var out bytes.Buffer
+		s := &StdoutSender{cfg: Config{}, out: &out}
+		if err := s.Send(draft); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		for _, want := range []string{"To:      john@example.com", "Cc:      boss@example.com", "Subject: Rapport", "report.pdf (application/pdf, 2.0 KiB)", "<p>Bonjour</p>"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output does not contain %q:\n%s", want, out.String())
+			}
+		}
+		if strings.Contains(out.String(), "Bcc:") {
+			t.Errorf("output must not contain an empty Bcc line:\n%s", out.String())
+		}
+	})
+
+	t.Run("json with body file", func(t *testing.T) {
+		var out bytes.Buffer
+		s := &StdoutSender{cfg: Config{Format: FormatJSON, Body: BodyFile}, out: &out}
+		if err := s.Send(draft); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		var got report
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, out.String())
+		}
+		t.Cleanup(func() { _ = os.Remove(got.HTMLFile) })
+
+		if got.Subject != "Rapport" || len(got.Attachments) != 1 || got.Attachments[0].Size != 2048 {
+			t.Errorf("report = %+v", got)
+		}
+		if got.HTMLBody != "" {
+			t.Errorf("html_body = %q, want empty in file mode", got.HTMLBody)
+		}
+		body, err := os.ReadFile(got.HTMLFile)
+		if err != nil || string(body) != draft.HTMLBody {
+			t.Errorf("body file content = %q (%v)", body, err)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		s := &StdoutSender{cfg: Config{Format: "xml"}, out: &bytes.Buffer{}}
+		if err := s.Send(draft); err == nil {
+			t.Errorf("Send() error = nil, want error")
+		}
+	})
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		name string
+		size int64
+		want string
+	}{
+		{name: "bytes", size: 512, want: "512 B"},
+		{name: "kibibytes", size: 1536, want: "1.5 KiB"},
+		{name: "mebibytes", size: 5 * 1024 * 1024, want: "5.0 MiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatSize(tt.size); got != tt.want {
+				t.Errorf("formatSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
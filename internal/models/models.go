@@ -1,11 +1,42 @@
 package models
 
+import (
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+)
+
 // TemplateRef represents a template file found in the templates directory.
 type TemplateRef struct {
 	// Name is the display name of the template (e.g. "invitation.html").
 	Name string
 	// Path is the relative or absolute path to the template file.
 	Path string
+	// Root is the templates directory of the search path the template was found in.
+	Root string
+	// Category is the subdirectory of the template, relative to the templates directory
+	// (e.g. "relances" or "relances/clients"). It is empty for top-level templates.
+	Category string
+	// Title is the human readable name of the template, from its frontmatter.
+	Title string
+	// Description explains what the template is for, from its frontmatter.
+	Description string
+	// Tags are keywords from the frontmatter, used to find the template.
+	Tags []string
+	// Owner is the person or team maintaining the template, from its frontmatter.
+	Owner string
+	// Language is the language of the email (e.g. "fr"), from its frontmatter.
+	Language string
+}
+
+// DisplayName returns the title of the template, or its file name if it has none.
+func (t TemplateRef) DisplayName() string {
+	if t.Title != "" {
+		return t.Title
+	}
+	return t.Name
 }
 
 // TemplateFilter represents a filter applied to a variable in the template.
@@ -36,28 +67,184 @@ type RenderedTemplate struct {
 	Subject string
 	// HTML is the final HTML string to be used as the email body.
 	HTML string
-	// To is the rendered recipient email address from template.
+	// Text is the plain-text alternative of the HTML body.
+	Text string
+	// To is the rendered recipient list from template (comma or semicolon separated).
 	To string
-	// Cc is the rendered carbon copy recipient email address from template.
+	// Cc is the rendered carbon copy recipient list from template.
 	Cc string
-	// Bcc is the rendered blind carbon copy recipient email address from template.
+	// Bcc is the rendered blind carbon copy recipient list from template.
 	Bcc string
+	// From is the rendered sender address from template, used to send on behalf of another mailbox.
+	From string
+	// ReplyTo is the rendered reply-to address list from template.
+	ReplyTo string
+	// Importance is the importance of the email. Empty means the backend default.
+	Importance Importance
+	// Sensitivity is the sensitivity of the email. Empty means the backend default.
+	Sensitivity Sensitivity
+	// ReadReceipt requests a read receipt from the recipients.
+	ReadReceipt bool
+	// DeliveryReceipt requests a delivery receipt.
+	DeliveryReceipt bool
+	// Headers are the custom internet headers from template, keyed by header name.
+	Headers map[string]string
+	// Event is the meeting the email invites the recipients to, if any.
+	Event *Event
+	// Files are the absolute paths of the files attached by the template frontmatter.
+	Files []string
+	// Attachments are the inline images referenced by the HTML through "cid:" URLs.
+	Attachments []Attachment
+	// PostProcess are the post-processing steps requested by the template.
+	PostProcess []PostProcessStep
+}
+
+// PostProcessStep selects a post-processor by name, with its options.
+type PostProcessStep struct {
+	// Name is the name the processor is registered under (e.g. "inline_css").
+	Name string
+	// Options are the processor-specific options.
+	Options map[string]any
 }
 
 // DraftEmail represents the email draft command to be sent to the sender.
 type DraftEmail struct {
-	// To is the recipient email address.
-	To string
-	// Cc is the carbon copy recipient email address.
-	Cc string
-	// Bcc is the blind carbon copy recipient email address.
-	Bcc string
+	// To is the list of recipients.
+	To []mail.Address
+	// Cc is the list of carbon copy recipients.
+	Cc []mail.Address
+	// Bcc is the list of blind carbon copy recipients.
+	Bcc []mail.Address
+	// From is the sender the email is sent on behalf of. If nil, the backend sender is used.
+	From *mail.Address
+	// ReplyTo is the list of addresses replies are sent to.
+	ReplyTo []mail.Address
 	// Subject is the email subject.
 	Subject string
+	// Importance is the importance of the email. Empty means the backend default.
+	Importance Importance
+	// Sensitivity is the sensitivity of the email. Empty means the backend default.
+	Sensitivity Sensitivity
+	// ReadReceipt requests a read receipt from the recipients.
+	ReadReceipt bool
+	// DeliveryReceipt requests a delivery receipt.
+	DeliveryReceipt bool
+	// Headers are custom internet headers (e.g. "X-Campaign"), keyed by header name.
+	Headers map[string]string
 	// HTMLBody is the HTML content of the email.
 	HTMLBody string
-	// Attachments is a list of file paths to attach to the email.
-	Attachments []string
+	// TextBody is the plain-text alternative of HTMLBody.
+	// When set, MIME backends send the body as multipart/alternative.
+	TextBody string
+	// Attachments is the list of files attached to the email.
+	Attachments []Attachment
+	// Event turns the email into a meeting invitation, sent as an iCalendar request part by the
+	// MIME backends. The outlook and graph backends only attach it as an .ics file: their drafts
+	// are regular emails, not meeting requests (see calendar.WithInvitation).
+	Event *Event
+}
+
+// Event is a meeting invitation sent with an email.
+type Event struct {
+	// UID identifies the event. Sending an invitation with the same UID updates the meeting.
+	UID string
+	// Summary is the title of the meeting.
+	Summary string
+	// Description is the plain-text description of the meeting.
+	Description string
+	// Location is where the meeting takes place.
+	Location string
+	// Start is the start of the meeting.
+	Start time.Time
+	// End is the end of the meeting. For all-day events, it is the day after the last day.
+	End time.Time
+	// AllDay marks an event lasting whole days, Start and End being dates.
+	AllDay bool
+	// Organizer is the person the replies are sent to. If nil, the sender of the email is used.
+	Organizer *mail.Address
+	// Attendees are the required participants.
+	Attendees []mail.Address
+	// OptionalAttendees are the optional participants.
+	OptionalAttendees []mail.Address
+}
+
+// Importance is the importance of an email.
+type Importance string
+
+// Importance values.
+const (
+	ImportanceLow    Importance = "low"
+	ImportanceNormal Importance = "normal"
+	ImportanceHigh   Importance = "high"
+)
+
+// Sensitivity is the sensitivity of an email.
+type Sensitivity string
+
+// Sensitivity values.
+const (
+	SensitivityNormal       Sensitivity = "normal"
+	SensitivityPersonal     Sensitivity = "personal"
+	SensitivityPrivate      Sensitivity = "private"
+	SensitivityConfidential Sensitivity = "confidential"
+)
+
+// Attachment represents a file attached to the email, read from disk or generated in memory.
+type Attachment struct {
+	// Path is the source file path. It is ignored when Content is set.
+	Path string
+	// Content is the in-memory content of the attachment.
+	Content []byte
+	// Name is the file name shown to the recipient. Defaults to the base name of Path.
+	Name string
+	// ContentType is the MIME type. Defaults to a type guessed from the file name extension.
+	ContentType string
+	// ContentID marks the attachment as inline: the HTML body references it as "cid:<ContentID>".
+	ContentID string
+}
+
+// FileName returns the file name shown to the recipient.
+func (a Attachment) FileName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return filepath.Base(a.Path)
+}
+
+// MediaType returns the MIME type of the attachment.
+func (a Attachment) MediaType() string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+	if t := mime.TypeByExtension(filepath.Ext(a.FileName())); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// Inline reports whether the attachment is displayed inside the HTML body.
+func (a Attachment) Inline() bool {
+	return a.ContentID != ""
+}
+
+// Read returns the content of the attachment.
+func (a Attachment) Read() ([]byte, error) {
+	if a.Content != nil {
+		return a.Content, nil
+	}
+	return os.ReadFile(a.Path)
+}
+
+// Size returns the size of the attachment in bytes.
+func (a Attachment) Size() (int64, error) {
+	if a.Content != nil {
+		return int64(len(a.Content)), nil
+	}
+	info, err := os.Stat(a.Path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
 }
 
 // Options represents the application configuration options.
@@ -68,12 +255,26 @@ type Options struct {
 	// If non-nil but empty string, the flag was provided but empty (list available templates).
 	// If non-nil with content, use the specified template path.
 	Template *string
-	// To is the default recipient email address from flags.
-	To string
-	// Cc is the default carbon copy recipient email address from flags.
-	Cc string
-	// Bcc is the blind carbon copy recipient email address from flags.
-	Bcc string
+	// To is the list of recipients from --to flags. If non-empty, it replaces the template recipients.
+	To []string
+	// Cc is the list of carbon copy recipients from --cc flags. If non-empty, it replaces the template value.
+	Cc []string
+	// Bcc is the list of blind carbon copy recipients from --bcc flags. If non-empty, it replaces the template value.
+	Bcc []string
+	// AddTo is the list of recipients from --add-to flags, added to the template recipients.
+	AddTo []string
+	// AddCc is the list of carbon copy recipients from --add-cc flags, added to the template value.
+	AddCc []string
+	// AddBcc is the list of blind carbon copy recipients from --add-bcc flags, added to the template value.
+	AddBcc []string
+	// NoTemplateRecipients drops the recipients defined in the template frontmatter.
+	NoTemplateRecipients bool
+	// Attachments is the list of extra file paths from --attach flags.
+	Attachments []string
+	// PostProcess are the post-processing steps of the configuration file, run after those of the template.
+	PostProcess []PostProcessStep
+	// TemplatesDirs is the templates search path of the configuration file, in order of precedence.
+	TemplatesDirs []string
 	// KV is a pointer to the key-value pairs string. If nil, the flag was not provided.
 	// If non-nil but empty string, the flag was provided but empty (show required variables).
 	// If non-nil with content, parse and use the values.
@@ -0,0 +1,400 @@
+// Package plaintext converts HTML email bodies to readable plain text,
+// used as the text/plain alternative of the messages.
+package plaintext
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"mailmate/internal/htmltoken"
+)
+
+// FromHTML returns a plain-text rendering of an HTML body:
+//   - headings are underlined (h1, h2) or prefixed with "#" (h3 to h6),
+//   - list items are prefixed with "-" or their number, and indented when nested,
+//   - links are replaced by their text followed by a footnote reference, the URLs being listed at the end,
+//   - table cells are separated by "|", header rows are underlined,
+//   - quotes are prefixed with ">", preformatted text is kept as is,
+//   - images are replaced by their alt text.
+func FromHTML(body string) string {
+	c := &converter{}
+	tokens := htmltoken.New(body)
+	for {
+		tok, ok := tokens.Next()
+		if !ok {
+			break
+		}
+		switch tok.Kind {
+		case htmltoken.Text:
+			c.text(html.UnescapeString(tok.Data))
+		case htmltoken.StartTag:
+			c.start(tok.Name, tok.Attrs)
+			if tok.SelfClosing {
+				c.end(tok.Name)
+			}
+		case htmltoken.EndTag:
+			c.end(tok.Name)
+		}
+	}
+	return c.result()
+}
+
+// list is an open <ul> or <ol> element.
+type list struct {
+	ordered bool
+	n       int
+	// prefix is the index of the list indentation in converter.prefixes.
+	prefix int
+}
+
+// link is an open <a> element.
+type link struct {
+	href string
+	text strings.Builder
+}
+
+// converter accumulates the text rendering while the HTML tokens are read.
+type converter struct {
+	out strings.Builder
+	// line is the current line, without its prefix.
+	line strings.Builder
+	// linePrefix is the prefix of the current line, fixed when its first word is written.
+	linePrefix string
+	// breaks is the number of line breaks to write before the next word.
+	breaks int
+	// space records whether a space must separate the next word from the previous one.
+	space bool
+	// blank records whether the last written line is empty.
+	blank bool
+
+	// prefixes are the indentations and quote markers of the open blocks.
+	prefixes []string
+	// marker is the list item marker ("- ", "1. ") written in place of the list indentation on the next line.
+	marker    string
+	markerIdx int
+
+	lists []*list
+	// quotes are the indexes of the open <blockquote> markers in prefixes.
+	quotes    []int
+	links     []*link
+	footnotes []string
+	// headerRow records whether the current table row holds header cells.
+	headerRow bool
+	// skip is the depth of elements whose content is not rendered (head, script, style...).
+	skip int
+	// pre is the depth of elements whose whitespace is preserved.
+	pre int
+}
+
+// start handles a start tag.
+func (c *converter) start(name string, attrs map[string]string) {
+	switch name {
+	case "head", "script", "style", "title", "template":
+		c.skip++
+	}
+	if c.skip > 0 {
+		return
+	}
+
+	switch name {
+	case "br":
+		c.newline()
+	case "p", "div", "section", "article", "header", "footer", "dl", "center":
+		c.block(2)
+	case "h1", "h2":
+		c.block(2)
+	case "h3", "h4", "h5", "h6":
+		c.block(2)
+		c.word(strings.Repeat("#", int(name[1]-'0')))
+		c.space = true
+	case "ul", "ol":
+		if len(c.lists) == 0 {
+			c.block(2)
+		} else {
+			c.block(1)
+		}
+		c.lists = append(c.lists, &list{ordered: name == "ol", prefix: len(c.prefixes)})
+		c.prefixes = append(c.prefixes, "")
+	case "li":
+		c.block(1)
+		if len(c.lists) == 0 {
+			return
+		}
+		l := c.lists[len(c.lists)-1]
+		l.n++
+		c.marker = "- "
+		if l.ordered {
+			c.marker = fmt.Sprintf("%d. ", l.n)
+		}
+		c.markerIdx = l.prefix
+		c.prefixes[l.prefix] = strings.Repeat(" ", len(c.marker))
+	case "blockquote":
+		c.block(2)
+		c.quotes = append(c.quotes, len(c.prefixes))
+		c.prefixes = append(c.prefixes, "> ")
+	case "pre":
+		c.block(2)
+		c.pre++
+	case "hr":
+		c.block(1)
+		c.word(strings.Repeat("-", 40))
+		c.block(1)
+	case "table":
+		c.block(2)
+	case "tr":
+		c.block(1)
+		c.headerRow = false
+	case "td", "th":
+		if name == "th" {
+			c.headerRow = true
+		}
+		if c.line.Len() > 0 && c.breaks == 0 {
+			c.space = false
+			c.line.WriteString(" | ")
+		}
+	case "a":
+		c.links = append(c.links, &link{href: strings.TrimSpace(attrs["href"])})
+	case "img":
+		if alt := strings.TrimSpace(attrs["alt"]); alt != "" {
+			c.text("[" + alt + "]")
+		}
+	}
+}
+
+// end handles an end tag.
+func (c *converter) end(name string) {
+	switch name {
+	case "head", "script", "style", "title", "template":
+		if c.skip > 0 {
+			c.skip--
+		}
+		return
+	}
+	if c.skip > 0 {
+		return
+	}
+
+	switch name {
+	case "p", "div", "section", "article", "header", "footer", "dl", "center", "table":
+		c.block(2)
+	case "h1", "h2":
+		underline := "="
+		if name == "h2" {
+			underline = "-"
+		}
+		if c.line.Len() > 0 {
+			n := utf8.RuneCountInString(c.line.String())
+			c.newline()
+			c.word(strings.Repeat(underline, n))
+		}
+		c.block(2)
+	case "h3", "h4", "h5", "h6", "blockquote", "pre":
+		if name == "blockquote" && len(c.quotes) > 0 {
+			// End the quoted text, the blank line after it is written without the quote marker
+			c.breaks = 0
+			if c.line.Len() > 0 {
+				c.newline()
+			}
+			c.truncatePrefixes(c.quotes[len(c.quotes)-1])
+		}
+		if name == "pre" && c.pre > 0 {
+			c.pre--
+		}
+		c.block(2)
+	case "ul", "ol":
+		if len(c.lists) > 0 {
+			c.flush()
+			c.truncatePrefixes(c.lists[len(c.lists)-1].prefix)
+		}
+		if len(c.lists) == 0 {
+			c.block(2)
+		} else {
+			c.block(1)
+		}
+	case "li", "dt", "dd":
+		c.block(1)
+	case "tr":
+		if c.headerRow && c.line.Len() > 0 {
+			n := utf8.RuneCountInString(c.line.String())
+			c.newline()
+			c.word(strings.Repeat("-", n))
+		}
+		c.headerRow = false
+		c.block(1)
+	case "a":
+		if len(c.links) == 0 {
+			return
+		}
+		l := c.links[len(c.links)-1]
+		c.links = c.links[:len(c.links)-1]
+		if n := c.footnote(l.href, strings.TrimSpace(l.text.String())); n > 0 {
+			c.line.WriteString(fmt.Sprintf(" [%d]", n))
+		}
+	}
+}
+
+// truncatePrefixes closes the block owning prefixes[n] and the blocks opened inside it,
+// which badly nested HTML may leave open (e.g. a list not closed before its quote).
+func (c *converter) truncatePrefixes(n int) {
+	c.prefixes = c.prefixes[:n]
+	for len(c.lists) > 0 && c.lists[len(c.lists)-1].prefix >= n {
+		c.lists = c.lists[:len(c.lists)-1]
+	}
+	for len(c.quotes) > 0 && c.quotes[len(c.quotes)-1] >= n {
+		c.quotes = c.quotes[:len(c.quotes)-1]
+	}
+	if c.markerIdx >= n {
+		c.marker = ""
+	}
+}
+
+// footnote records the URL of a link and returns its footnote number,
+// or 0 if the link needs no footnote (anchors, or URLs already readable in the text).
+func (c *converter) footnote(href, text string) int {
+	lower := strings.ToLower(href)
+	switch {
+	case href == "", strings.HasPrefix(href, "#"), strings.HasPrefix(lower, "javascript:"), strings.HasPrefix(lower, "cid:"):
+		return 0
+	case text == "":
+		return 0
+	}
+	readable := strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(href, "mailto:"), "https://"), "http://")
+	if text == href || text == readable || text == strings.TrimSuffix(readable, "/") {
+		return 0
+	}
+	for i, f := range c.footnotes {
+		if f == href {
+			return i + 1
+		}
+	}
+	c.footnotes = append(c.footnotes, href)
+	return len(c.footnotes)
+}
+
+// text writes a text node.
+func (c *converter) text(s string) {
+	if c.skip > 0 || s == "" {
+		return
+	}
+
+	if c.pre > 0 {
+		for i, segment := range strings.Split(s, "\n") {
+			if i > 0 {
+				c.newline()
+			}
+			if segment != "" {
+				c.flush()
+				c.startLine()
+				c.line.WriteString(segment)
+				c.writeLinkText(segment)
+			}
+		}
+		return
+	}
+
+	if isSpace(s[0]) {
+		c.space = true
+	}
+	for _, w := range strings.Fields(s) {
+		c.word(w)
+		c.space = true
+	}
+	if !isSpace(s[len(s)-1]) {
+		c.space = false
+	}
+}
+
+// word writes a word, preceded by the pending line breaks and space.
+func (c *converter) word(w string) {
+	c.flush()
+	if c.line.Len() == 0 {
+		c.startLine()
+	} else if c.space {
+		c.line.WriteString(" ")
+		c.writeLinkText(" ")
+	}
+	c.line.WriteString(w)
+	c.writeLinkText(w)
+	c.space = false
+}
+
+// writeLinkText records s as part of the text of the open links.
+func (c *converter) writeLinkText(s string) {
+	for _, l := range c.links {
+		l.text.WriteString(s)
+	}
+}
+
+// startLine fixes the prefix of the current line, consuming the pending list item marker.
+func (c *converter) startLine() {
+	if c.line.Len() > 0 {
+		return
+	}
+	var b strings.Builder
+	for i, p := range c.prefixes {
+		if c.marker != "" && i == c.markerIdx {
+			p = c.marker
+		}
+		b.WriteString(p)
+	}
+	c.marker = ""
+	c.linePrefix = b.String()
+}
+
+// block requests n line breaks before the next word: 1 starts a new line, 2 leaves a blank line.
+func (c *converter) block(n int) {
+	c.breaks = max(c.breaks, n)
+	c.space = false
+}
+
+// newline ends the current line, even if it is empty.
+func (c *converter) newline() {
+	c.flush()
+	c.out.WriteString(strings.TrimRight(c.linePrefix+c.line.String(), " ") + "\n")
+	c.blank = c.line.Len() == 0
+	c.line.Reset()
+	c.linePrefix = strings.Join(c.prefixes, "")
+	c.space = false
+}
+
+// flush writes the pending line breaks, if some text precedes them.
+func (c *converter) flush() {
+	if c.breaks == 0 {
+		return
+	}
+	breaks := c.breaks
+	c.breaks = 0
+	if c.line.Len() > 0 {
+		c.newline()
+	}
+	if breaks > 1 && c.out.Len() > 0 && !c.blank {
+		c.out.WriteString(strings.TrimRight(strings.Join(c.prefixes, ""), " ") + "\n")
+		c.blank = true
+	}
+}
+
+// result returns the rendered text followed by the link footnotes.
+func (c *converter) result() string {
+	c.breaks = 0
+	if c.line.Len() > 0 {
+		c.newline()
+	}
+	text := strings.Trim(c.out.String(), "\n")
+
+	if len(c.footnotes) > 0 {
+		var b strings.Builder
+		b.WriteString(text + "\n\n")
+		for i, href := range c.footnotes {
+			fmt.Fprintf(&b, "[%d] %s\n", i+1, href)
+		}
+		return b.String()
+	}
+	return text + "\n"
+}
+
+// isSpace reports whether b is an HTML whitespace character.
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
@@ -0,0 +1,65 @@
+package plaintext
+
+import "testing"
+
+func TestFromHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "paragraphs and line breaks",
+			html: "<html><head><title>x</title><style>p { color: red; }</style></head><body><p>Bonjour  <b>John</b>,</p>\n<p>Ligne 1<br>Ligne&nbsp;2 &amp; fin</p></body></html>",
+			want: "Bonjour John,\n\nLigne 1\nLigne 2 & fin\n",
+		},
+		{
+			name: "headings",
+			html: "<h1>Rapport</h1><h2>Résumé</h2><p>Texte</p><h3>Détails</h3>",
+			want: "Rapport\n=======\n\nRésumé\n------\n\nTexte\n\n### Détails\n",
+		},
+		{
+			name: "nested lists",
+			html: "<p>Points :</p><ul><li>Un</li><li>Deux<ol><li>A</li><li>B</li></ol></li><li>Trois</li></ul><p>Fin</p>",
+			want: "Points :\n\n- Un\n- Deux\n  1. A\n  2. B\n- Trois\n\nFin\n",
+		},
+		{
+			name: "links as footnotes",
+			html: `<p>Voir <a href="https://example.com/facture">la facture</a>, <a href="https://example.com">example.com</a>, <a href="mailto:jane@example.com">jane@example.com</a> et <a href='https://example.com/facture'>encore</a>.</p>`,
+			want: "Voir la facture [1], example.com, jane@example.com et encore [1].\n\n[1] https://example.com/facture\n",
+		},
+		{
+			name: "table",
+			html: "<table><tr><th>Article</th><th>Prix</th></tr><tr><td>Stylo</td><td>2 €</td></tr><tr><td>Cahier</td><td>4 €</td></tr></table><p>Total</p>",
+			want: "Article | Prix\n--------------\nStylo | 2 €\nCahier | 4 €\n\nTotal\n",
+		},
+		{
+			name: "quote, preformatted text, rule and image",
+			html: "<blockquote><p>Cité</p><p>Deux</p></blockquote><pre>a  b\n  c</pre><hr><img src=\"cid:logo\" alt=\"Logo\"><!-- <p>commentaire</p> -->",
+			want: "> Cité\n>\n> Deux\n\na  b\n  c\n\n----------------------------------------\n[Logo]\n",
+		},
+		{
+			name: "list left open in a quote",
+			html: "<blockquote><ul><li>x</blockquote><li>y</ul>",
+			want: "> - x\n\ny\n",
+		},
+		{
+			name: "quote left open in a list",
+			html: "<ul><li>a<blockquote><p>q</ul><p>b</p></blockquote><p>c</p>",
+			want: "- a\n  >\n  > q\n\nb\n\nc\n",
+		},
+		{
+			name: "stray end tags",
+			html: "</blockquote></ul><li>x</li>",
+			want: "x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromHTML(tt.html); got != tt.want {
+				t.Errorf("FromHTML() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
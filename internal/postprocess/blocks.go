@@ -0,0 +1,161 @@
+package postprocess
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"mailmate/internal/homedir"
+	"mailmate/internal/models"
+	"mailmate/internal/plaintext"
+)
+
+// Positions of the disclaimer.
+const (
+	// PositionTop inserts the block at the start of the body.
+	PositionTop = "top"
+	// PositionBottom inserts the block at the end of the body.
+	PositionBottom = "bottom"
+)
+
+var (
+	// bodyStartRegex matches the <body> start tag.
+	bodyStartRegex = regexp.MustCompile(`(?i)<body\b[^>]*>`)
+	// bodyEndRegex matches the </body> end tag.
+	bodyEndRegex = regexp.MustCompile(`(?i)</body\s*>`)
+)
+
+// init registers the signature and disclaimer processors.
+func init() {
+	Register("signature", func(decode func(v any) error) (Processor, error) {
+		var cfg blockConfig
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		return &block{cfg: cfg, position: PositionBottom, separator: "-- \n"}, nil
+	})
+
+	Register("disclaimer", func(decode func(v any) error) (Processor, error) {
+		var cfg struct {
+			blockConfig `yaml:",inline"`
+			// Position is PositionBottom (default) or PositionTop.
+			Position string `yaml:"position"`
+		}
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		position := strings.ToLower(cfg.Position)
+		switch position {
+		case "":
+			position = PositionBottom
+		case PositionTop, PositionBottom:
+		default:
+			return nil, fmt.Errorf("unknown position: %s", cfg.Position)
+		}
+		return &block{cfg: cfg.blockConfig, position: position}, nil
+	})
+}
+
+// blockConfig holds the content of a block added to the body, inline or read from files.
+type blockConfig struct {
+	// HTML is the HTML content of the block.
+	HTML string `yaml:"html"`
+	// File is the path of a file holding the HTML content, used if HTML is empty.
+	File string `yaml:"file"`
+	// Text is the plain-text version. Defaults to a conversion of the HTML content.
+	Text string `yaml:"text"`
+	// TextFile is the path of a file holding the plain-text version, used if Text is empty.
+	TextFile string `yaml:"text_file"`
+}
+
+// validate checks that the block has some content.
+func (c blockConfig) validate() error {
+	if c.HTML == "" && c.File == "" {
+		return errors.New("html or file is required")
+	}
+	return nil
+}
+
+// block adds a fixed block (signature, disclaimer) to the HTML and text bodies.
+type block struct {
+	cfg      blockConfig
+	position string
+	// separator is written in the text body before the block, after a blank line.
+	separator string
+}
+
+// Process implements Processor.
+func (b *block) Process(ctx Context, draft *models.DraftEmail) error {
+	html, err := readContent(b.cfg.HTML, b.cfg.File, ctx.TemplateDir)
+	if err != nil {
+		return err
+	}
+	text, err := readContent(b.cfg.Text, b.cfg.TextFile, ctx.TemplateDir)
+	if err != nil {
+		return err
+	}
+	if text == "" {
+		text = plaintext.FromHTML(html)
+	}
+
+	draft.HTMLBody = insertHTML(draft.HTMLBody, html, b.position)
+
+	// Drafts without a text alternative are left without one
+	if draft.TextBody == "" {
+		return nil
+	}
+	text = b.separator + strings.Trim(text, "\n") + "\n"
+	body := strings.Trim(draft.TextBody, "\n")
+	if b.position == PositionTop {
+		draft.TextBody = text + "\n" + body + "\n"
+	} else {
+		draft.TextBody = body + "\n\n" + text
+	}
+	return nil
+}
+
+// readContent returns value, or else the content of the file at path.
+// Relative paths are resolved from dir, and a leading "~/" designates the home directory.
+func readContent(value, path, dir string) (string, error) {
+	if value != "" || path == "" {
+		return value, nil
+	}
+	if expanded, err := homedir.Expand(path); err == nil {
+		path = expanded
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return string(content), nil
+}
+
+// insertHTML inserts snippet inside the <body> element of html, at the given position.
+// Without a <body> element, the snippet is added at the start or the end of html.
+func insertHTML(html, snippet, position string) string {
+	if position == PositionTop {
+		if loc := bodyStartRegex.FindStringIndex(html); loc != nil {
+			return html[:loc[1]] + snippet + html[loc[1]:]
+		}
+		return snippet + html
+	}
+
+	locs := bodyEndRegex.FindAllStringIndex(html, -1)
+	if len(locs) == 0 {
+		return html + snippet
+	}
+	end := locs[len(locs)-1][0]
+	return html[:end] + snippet + html[end:]
+}
@@ -0,0 +1,28 @@
+package postprocess
+
+import (
+	"fmt"
+
+	"mailmate/internal/cssinline"
+	"mailmate/internal/models"
+)
+
+// init registers the CSS inliner.
+func init() {
+	Register("inline_css", func(func(v any) error) (Processor, error) {
+		return inlineCSS{}, nil
+	})
+}
+
+// inlineCSS moves the CSS rules of the HTML body into style attributes. See cssinline.Inline.
+type inlineCSS struct{}
+
+// Process implements Processor.
+func (inlineCSS) Process(ctx Context, draft *models.DraftEmail) error {
+	html, err := cssinline.Inline(draft.HTMLBody, append([]string{ctx.TemplateDir}, ctx.TemplatesDirs...)...)
+	if err != nil {
+		return fmt.Errorf("inlining CSS: %w", err)
+	}
+	draft.HTMLBody = html
+	return nil
+}
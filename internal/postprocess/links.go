@@ -0,0 +1,120 @@
+package postprocess
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"mailmate/internal/models"
+)
+
+var (
+	// hrefRegex matches the href attribute of a link.
+	// The value is captured in group 2 (double quotes), 3 (single quotes) or 4 (unquoted).
+	hrefRegex = regexp.MustCompile(`(?is)<a\b[^>]*?\shref\s*=\s*("([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	// textURLRegex matches web URLs in the text body.
+	textURLRegex = regexp.MustCompile(`https?://[^\s<>\[\]()"]+`)
+)
+
+// init registers the link rewriter.
+func init() {
+	Register("rewrite_links", func(decode func(v any) error) (Processor, error) {
+		var cfg linksConfig
+		if err := decode(&cfg); err != nil {
+			return nil, err
+		}
+		if len(cfg.Params) == 0 && cfg.Prefix == "" {
+			return nil, errors.New("params or prefix is required")
+		}
+		return &rewriteLinks{cfg: cfg}, nil
+	})
+}
+
+// linksConfig holds the options of the link rewriter.
+type linksConfig struct {
+	// Params are query parameters added to the links (e.g. utm_source), unless already set.
+	Params map[string]string `yaml:"params"`
+	// Prefix routes the links through a redirect URL: the original URL, query-escaped, is appended to it.
+	Prefix string `yaml:"prefix"`
+	// Domains restricts the rewriting to the links to these domains and their subdomains.
+	Domains []string `yaml:"domains"`
+}
+
+// rewriteLinks rewrites the web links of the HTML body, and the same URLs in the text body,
+// for click tracking or campaign parameters.
+type rewriteLinks struct {
+	cfg linksConfig
+}
+
+// Process implements Processor.
+func (r *rewriteLinks) Process(_ Context, draft *models.DraftEmail) error {
+	body := draft.HTMLBody
+	var out strings.Builder
+	last := 0
+	for _, m := range hrefRegex.FindAllStringSubmatchIndex(body, -1) {
+		// Find the group holding the value
+		start, end := m[4], m[5]
+		for g := 3; start == -1 && g <= 4; g++ {
+			start, end = m[2*g], m[2*g+1]
+		}
+
+		rewritten, ok := r.rewrite(html.UnescapeString(body[start:end]))
+		if !ok {
+			continue
+		}
+		out.WriteString(body[last:start])
+		out.WriteString(html.EscapeString(rewritten))
+		last = end
+	}
+	out.WriteString(body[last:])
+	draft.HTMLBody = out.String()
+
+	draft.TextBody = textURLRegex.ReplaceAllStringFunc(draft.TextBody, func(link string) string {
+		if rewritten, ok := r.rewrite(link); ok {
+			return rewritten
+		}
+		return link
+	})
+	return nil
+}
+
+// rewrite returns the rewritten link, or false if the link must be left untouched.
+func (r *rewriteLinks) rewrite(link string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !r.matchesDomain(u.Hostname()) {
+		return "", false
+	}
+
+	if len(r.cfg.Params) > 0 {
+		query := u.Query()
+		for k, v := range r.cfg.Params {
+			if !query.Has(k) {
+				query.Set(k, v)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	rewritten := u.String()
+	if r.cfg.Prefix != "" {
+		rewritten = r.cfg.Prefix + url.QueryEscape(rewritten)
+	}
+	return rewritten, true
+}
+
+// matchesDomain reports whether links to host must be rewritten.
+func (r *rewriteLinks) matchesDomain(host string) bool {
+	if len(r.cfg.Domains) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, d := range r.cfg.Domains {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
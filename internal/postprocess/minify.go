@@ -0,0 +1,56 @@
+package postprocess
+
+import (
+	"regexp"
+	"strings"
+
+	"mailmate/internal/models"
+)
+
+var (
+	// htmlCommentRegex matches HTML comments, except Outlook conditional comments
+	// (<!--[if mso]>...<![endif]-->, <!--[if !mso]><!-->...<!--<![endif]-->).
+	htmlCommentRegex = regexp.MustCompile(`(?s)<!--(?:[^\[<>].*?)?-->`)
+	// preservedRegex matches the elements whose whitespace is significant.
+	preservedRegex = regexp.MustCompile(`(?is)<(pre|textarea|script)\b.*?</(pre|textarea|script)\s*>`)
+	// whitespaceRegex matches runs of whitespace.
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// init registers the HTML minifier.
+func init() {
+	Register("minify", func(func(v any) error) (Processor, error) {
+		return minify{}, nil
+	})
+}
+
+// minify reduces the size of the HTML body: comments are removed and whitespace is collapsed.
+// Outlook conditional comments and the content of <pre>, <textarea> and <script> are kept.
+type minify struct{}
+
+// Process implements Processor.
+func (minify) Process(_ Context, draft *models.DraftEmail) error {
+	html := draft.HTMLBody
+	var out strings.Builder
+	last := 0
+	for _, loc := range preservedRegex.FindAllStringIndex(html, -1) {
+		out.WriteString(minifyHTML(html[last:loc[0]]))
+		out.WriteString(html[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.WriteString(minifyHTML(html[last:]))
+	draft.HTMLBody = strings.TrimSpace(out.String())
+	return nil
+}
+
+// minifyHTML removes the comments of s and collapses its whitespace.
+// Runs spanning several lines become a single line break, to keep lines reasonably short.
+func minifyHTML(s string) string {
+	s = htmlCommentRegex.ReplaceAllString(s, "")
+	return whitespaceRegex.ReplaceAllStringFunc(s, func(ws string) string {
+		if strings.ContainsAny(ws, "\r\n") {
+			return "\n"
+		}
+		return " "
+	})
+}
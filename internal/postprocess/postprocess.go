@@ -0,0 +1,162 @@
+// Package postprocess transforms drafts after the template is rendered.
+//
+// A pipeline is an ordered list of processors, selected by name from the template
+// frontmatter or from the configuration file:
+//
+//	postprocess:
+//	  - inline_css
+//	  - name: signature
+//	    file: ~/signature.html
+package postprocess
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"mailmate/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Context holds what processors need to know about the rendered template.
+type Context struct {
+	// TemplateDir is the absolute directory of the template. Relative paths in options are resolved from it.
+	TemplateDir string
+	// TemplatesDirs are the absolute templates directories, in order of precedence. The stylesheets
+	// of the layouts and partials, not found next to the template, are resolved from them.
+	TemplatesDirs []string
+}
+
+// Processor transforms a draft after the template is rendered.
+// It may change the bodies, the subject or the attachments of the draft.
+type Processor interface {
+	Process(ctx Context, draft *models.DraftEmail) error
+}
+
+// Factory creates a Processor from its options.
+// decode unmarshals the options of the step into the value it is given.
+type Factory func(decode func(v any) error) (Processor, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a processor available under the given name.
+// It panics if the name is registered twice.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, dup := registry[name]; dup {
+		panic(fmt.Errorf("postprocess: processor %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// Processors returns the sorted names of all registered processors.
+func Processors() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Pipeline is an ordered list of processors.
+type Pipeline []Processor
+
+// New creates the pipeline running the given steps in order.
+func New(steps []models.PostProcessStep) (Pipeline, error) {
+	pipeline := make(Pipeline, 0, len(steps))
+	for _, step := range steps {
+		registryMu.RLock()
+		factory, ok := registry[step.Name]
+		registryMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown post-processor %q (available: %v)", step.Name, Processors())
+		}
+
+		p, err := factory(decoder(step.Options))
+		if err != nil {
+			return nil, fmt.Errorf("configuring post-processor %q: %w", step.Name, err)
+		}
+		pipeline = append(pipeline, p)
+	}
+	return pipeline, nil
+}
+
+// Process runs every processor of the pipeline on the draft.
+func (p Pipeline) Process(ctx Context, draft *models.DraftEmail) error {
+	for _, processor := range p {
+		if err := processor.Process(ctx, draft); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decoder returns a function decoding the options into the value it is given.
+func decoder(options map[string]any) func(v any) error {
+	return func(v any) error {
+		if len(options) == 0 {
+			return nil
+		}
+		data, err := yaml.Marshal(options)
+		if err != nil {
+			return fmt.Errorf("encoding options: %w", err)
+		}
+		// Unknown options are reported, so that typos do not go unnoticed
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("invalid options: %w", err)
+		}
+		return nil
+	}
+}
+
+// Steps is a list of pipeline steps read from YAML. Each item is either a processor name,
+// or a mapping with a "name" key and the options of the processor:
+//
+//	postprocess:
+//	  - minify
+//	  - name: disclaimer
+//	    html: "<p>Confidentiel</p>"
+type Steps []models.PostProcessStep
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (s *Steps) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: postprocess must be a list", node.Line)
+	}
+
+	steps := make(Steps, 0, len(node.Content))
+	for _, item := range node.Content {
+		switch item.Kind {
+		case yaml.ScalarNode:
+			steps = append(steps, models.PostProcessStep{Name: item.Value})
+		case yaml.MappingNode:
+			var options map[string]any
+			if err := item.Decode(&options); err != nil {
+				return err
+			}
+			name, _ := options["name"].(string)
+			if name == "" {
+				return fmt.Errorf("line %d: postprocess step without a name", item.Line)
+			}
+			delete(options, "name")
+			steps = append(steps, models.PostProcessStep{Name: name, Options: options})
+		default:
+			return fmt.Errorf("line %d: invalid postprocess step", item.Line)
+		}
+	}
+	*s = steps
+	return nil
+}
@@ -0,0 +1,162 @@
+package postprocess
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mailmate/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStepsUnmarshal(t *testing.T) {
+	input := `
+postprocess:
+  - inline_css
+  - name: signature
+    file: signature.html
+`
+	var cfg struct {
+		PostProcess Steps `yaml:"postprocess"`
+	}
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := Steps{
+		{Name: "inline_css"},
+		{Name: "signature", Options: map[string]any{"file": "signature.html"}},
+	}
+	if !reflect.DeepEqual(cfg.PostProcess, want) {
+		t.Errorf("Steps = %+v, want %+v", cfg.PostProcess, want)
+	}
+
+	for _, invalid := range []string{"postprocess: minify", "postprocess:\n  - file: a.html"} {
+		if err := yaml.Unmarshal([]byte(invalid), &cfg); err == nil {
+			t.Errorf("Unmarshal(%q) error = nil, want error", invalid)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []models.PostProcessStep
+	}{
+		{name: "unknown processor", steps: []models.PostProcessStep{{Name: "nope"}}},
+		{name: "missing option", steps: []models.PostProcessStep{{Name: "signature"}}},
+		{name: "unknown option", steps: []models.PostProcessStep{{Name: "signature", Options: map[string]any{"html": "x", "htm": "y"}}}},
+		{name: "invalid position", steps: []models.PostProcessStep{{Name: "disclaimer", Options: map[string]any{"html": "x", "position": "middle"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.steps); err == nil {
+				t.Errorf("New() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "signature.html"), []byte("<p>Jane Doe<br>ACME</p>"), 0o600); err != nil {
+		t.Fatalf("Failed to write signature: %v", err)
+	}
+	// "~bob" is a directory name, not the home directory of bob
+	if err := os.Mkdir(filepath.Join(dir, "~bob"), 0o700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "~bob", "signature.html"), []byte("<p>Bob</p>"), 0o600); err != nil {
+		t.Fatalf("Failed to write signature: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		steps    []models.PostProcessStep
+		draft    models.DraftEmail
+		wantHTML string
+		wantText string
+	}{
+		{
+			name:     "signature from file",
+			steps:    []models.PostProcessStep{{Name: "signature", Options: map[string]any{"file": "signature.html"}}},
+			draft:    models.DraftEmail{HTMLBody: "<html><body><p>Bonjour</p></body></html>", TextBody: "Bonjour\n"},
+			wantHTML: "<html><body><p>Bonjour</p><p>Jane Doe<br>ACME</p></body></html>",
+			wantText: "Bonjour\n\n-- \nJane Doe\nACME\n",
+		},
+		{
+			name:     "signature from a file of a ~ directory",
+			steps:    []models.PostProcessStep{{Name: "signature", Options: map[string]any{"file": "~bob/signature.html"}}},
+			draft:    models.DraftEmail{HTMLBody: "<p>Bonjour</p>"},
+			wantHTML: "<p>Bonjour</p><p>Bob</p>",
+		},
+		{
+			name: "disclaimer on top, without text body",
+			steps: []models.PostProcessStep{{Name: "disclaimer", Options: map[string]any{
+				"html":     "<p>CONFIDENTIEL</p>",
+				"position": "top",
+			}}},
+			draft:    models.DraftEmail{HTMLBody: `<body class="x"><p>Bonjour</p></body>`},
+			wantHTML: `<body class="x"><p>CONFIDENTIEL</p><p>Bonjour</p></body>`,
+		},
+		{
+			name: "minify then disclaimer with text",
+			steps: []models.PostProcessStep{
+				{Name: "minify"},
+				{Name: "disclaimer", Options: map[string]any{"html": "<small>Confidentiel</small>", "text": "Ce message est confidentiel."}},
+			},
+			draft: models.DraftEmail{
+				HTMLBody: "<html>\n  <body>\n    <!-- note -->\n    <p>Bonjour   <b>John</b></p>\n    <!--[if mso]><p>Outlook</p><![endif]-->\n    <pre>a\n  b</pre>\n  </body>\n</html>\n",
+				TextBody: "Bonjour John\n",
+			},
+			wantHTML: "<html>\n<body>\n<p>Bonjour <b>John</b></p>\n<!--[if mso]><p>Outlook</p><![endif]-->\n<pre>a\n  b</pre>\n<small>Confidentiel</small></body>\n</html>",
+			wantText: "Bonjour John\n\nCe message est confidentiel.\n",
+		},
+		{
+			name: "rewrite links",
+			steps: []models.PostProcessStep{{Name: "rewrite_links", Options: map[string]any{
+				"params":  map[string]any{"utm_source": "mailmate"},
+				"domains": []any{"example.com"},
+			}}},
+			draft: models.DraftEmail{
+				HTMLBody: `<a href="https://www.example.com/a?x=1&amp;y=2">a</a> <a href='mailto:jane@example.com'>b</a> <a href="https://other.org/">c</a> <a href="https://example.com/?utm_source=news">d</a>`,
+				TextBody: "a [1]\n\n[1] https://www.example.com/a?x=1&y=2\n",
+			},
+			wantHTML: `<a href="https://www.example.com/a?utm_source=mailmate&amp;x=1&amp;y=2">a</a> <a href='mailto:jane@example.com'>b</a> <a href="https://other.org/">c</a> <a href="https://example.com/?utm_source=news">d</a>`,
+			wantText: "a [1]\n\n[1] https://www.example.com/a?utm_source=mailmate&x=1&y=2\n",
+		},
+		{
+			name:     "redirect prefix",
+			steps:    []models.PostProcessStep{{Name: "rewrite_links", Options: map[string]any{"prefix": "https://track.example.com/r?u="}}},
+			draft:    models.DraftEmail{HTMLBody: `<a class="btn" href="https://example.com/a b">a</a>`},
+			wantHTML: `<a class="btn" href="https://track.example.com/r?u=https%3A%2F%2Fexample.com%2Fa%2520b">a</a>`,
+		},
+		{
+			name:     "inline css",
+			steps:    []models.PostProcessStep{{Name: "inline_css"}},
+			draft:    models.DraftEmail{HTMLBody: "<style>p { margin: 0 }</style><p>Bonjour</p>"},
+			wantHTML: `<p style="margin: 0">Bonjour</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := New(tt.steps)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			draft := tt.draft
+			if err := pipeline.Process(Context{TemplateDir: dir}, &draft); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if draft.HTMLBody != tt.wantHTML {
+				t.Errorf("HTMLBody =\n%q\nwant\n%q", draft.HTMLBody, tt.wantHTML)
+			}
+			if draft.TextBody != tt.wantText {
+				t.Errorf("TextBody =\n%q\nwant\n%q", draft.TextBody, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"mailmate/internal/starter"
+)
+
+// Init scaffolds a templates directory with the starter templates, layouts and partials,
+// and creates the configuration file at configPath pointing to it, unless it already exists.
+// The directory defaults to the first directory of MAILMATE_TEMPLATES_DIR, or else to
+// "templates" next to the configuration file. Existing templates are kept unless force is set.
+func Init(dir, configPath string, force bool) error {
+	if dir == "" {
+		if os.Getenv("MAILMATE_TEMPLATES_DIR") != "" {
+			dir = getTemplatesDirs(nil)[0]
+		} else if configPath != "" {
+			dir = filepath.Join(filepath.Dir(configPath), "templates")
+		} else {
+			dir = "templates"
+		}
+	}
+	// The configuration file must work from any directory
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolving templates directory: %w", err)
+	}
+
+	written, err := starter.WriteTemplates(dir, force)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Templates directory: %s\n", dir)
+	for _, path := range written {
+		fmt.Printf("  + %s\n", path)
+	}
+	if len(written) == 0 {
+		fmt.Println("  (starter templates already present, use --force to overwrite them)")
+	}
+
+	if configPath != "" {
+		created, err := starter.WriteConfig(configPath, dir)
+		if err != nil {
+			return err
+		}
+		if created {
+			fmt.Printf("Config file: %s\n", configPath)
+		} else {
+			fmt.Printf("Config file: %s already exists, add the templates directory to its templates_dirs\n", configPath)
+		}
+	}
+
+	fmt.Println("\nRun mailmate to pick a template, or edit the templates to match your needs.")
+	return nil
+}
+
+// builtinTemplatesDir returns a directory holding the starter templates, used when
+// no templates directory exists yet. The templates are refreshed in the user cache directory.
+func builtinTemplatesDir() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cache, "mailmate", "templates")
+	if _, err := starter.WriteTemplates(dir, true); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
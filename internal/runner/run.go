@@ -1,25 +1,177 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/mail"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"mailmate/internal/address"
+	"mailmate/internal/homedir"
 	"mailmate/internal/kv"
 	"mailmate/internal/mailer"
 	"mailmate/internal/models"
+	"mailmate/internal/postprocess"
 	"mailmate/internal/templates"
 	"mailmate/internal/tui"
+	"mailmate/internal/validator"
 )
 
-// getTemplatesDir returns the templates directory path.
-// It first checks the MAILMATE_TEMPLATES_DIR environment variable.
-// If not set, it defaults to "templates".
-func getTemplatesDir() string {
-	if dir := os.Getenv("MAILMATE_TEMPLATES_DIR"); dir != "" {
-		return dir
+// getTemplatesDirs returns the templates search path, in order of precedence.
+// It first checks the MAILMATE_TEMPLATES_DIR environment variable, a list of directories
+// separated by the OS path list separator (";" on Windows, ":" elsewhere).
+// If not set, it uses the directories of the configuration file, and defaults to "templates".
+// A leading "~/" designates the home directory.
+func getTemplatesDirs(configured []string) []string {
+	dirs := configured
+	if env := os.Getenv("MAILMATE_TEMPLATES_DIR"); env != "" {
+		dirs = filepath.SplitList(env)
 	}
-	return "templates"
+
+	var out []string
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		if expanded, err := homedir.Expand(dir); err == nil {
+			dir = expanded
+		}
+		out = append(out, dir)
+	}
+	if len(out) == 0 {
+		return []string{"templates"}
+	}
+	return out
+}
+
+// findTemplate returns the template designated by --template: either the path of the file,
+// or its path relative to the templates directories (e.g. "relances/facture.html"),
+// which designates the template taking precedence in the search path.
+func findTemplate(tmpls []models.TemplateRef, name string) (*models.TemplateRef, bool) {
+	// Normalize paths for comparison (handles / vs \ on Windows)
+	normalizedInput := filepath.Clean(name)
+	for i := range tmpls {
+		if filepath.Clean(tmpls[i].Path) == normalizedInput {
+			return &tmpls[i], true
+		}
+	}
+	for i := range tmpls {
+		if filepath.Join(filepath.FromSlash(tmpls[i].Category), tmpls[i].Name) == normalizedInput {
+			return &tmpls[i], true
+		}
+	}
+	return nil, false
+}
+
+// resolveRecipients parses the recipients of one field.
+// Replacement values (--to) override the template value, additional values (--add-to) are appended.
+func resolveRecipients(field, templateValue string, replace, add []string) ([]mail.Address, error) {
+	values := []string{templateValue}
+	if len(replace) > 0 {
+		values = replace
+	}
+	values = append(append([]string{}, values...), add...)
+
+	var addrs []mail.Address
+	for _, v := range values {
+		parsed, err := address.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q recipients: %w", field, err)
+		}
+		addrs = append(addrs, parsed...)
+	}
+	return addrs, nil
+}
+
+// resolveFrom parses the sender address of the template. It returns nil if the template has none.
+func resolveFrom(templateValue string) (*mail.Address, error) {
+	addrs, err := address.Parse(templateValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %q address: %w", "from", err)
+	}
+	switch len(addrs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &addrs[0], nil
+	default:
+		return nil, fmt.Errorf("invalid %q address: expected a single address, got %d", "from", len(addrs))
+	}
+}
+
+// addAttachments validates the extra attachment paths and appends them to attachments.
+// Files already attached are skipped, and the total size is checked against validator.MaxAttachmentSize.
+func addAttachments(attachments []models.Attachment, extra []string) ([]models.Attachment, error) {
+	seen := make(map[string]bool)
+	var total int64
+	for _, a := range attachments {
+		if a.Content == nil {
+			seen[a.Path] = true
+		}
+		if size, err := a.Size(); err == nil {
+			total += size
+		}
+	}
+
+	for _, path := range extra {
+		size, err := validator.ValidateAttachment(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachment: %w", err)
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("resolving absolute path for %s: %w", path, err)
+		}
+		if seen[absPath] {
+			continue
+		}
+		seen[absPath] = true
+		total += size
+		attachments = append(attachments, models.Attachment{Path: absPath})
+	}
+
+	if total > validator.MaxAttachmentSize {
+		return nil, fmt.Errorf("attachments are too large (%d MB in total, max %d MB)", total>>20, validator.MaxAttachmentSize>>20)
+	}
+	return attachments, nil
+}
+
+// displayTemplates prints the available templates grouped by category, with their metadata.
+func displayTemplates(tmpls []models.TemplateRef) {
+	fmt.Println("Available templates:")
+	category := ""
+	for _, tmpl := range tmpls {
+		if tmpl.Category != category {
+			category = tmpl.Category
+			fmt.Printf("\n%s/\n", category)
+		}
+		fmt.Printf("  - %s\n", tmpl.Path)
+
+		var title []string
+		if tmpl.Title != "" {
+			title = append(title, tmpl.Title)
+		}
+		if tmpl.Language != "" {
+			title = append(title, "["+tmpl.Language+"]")
+		}
+		if len(tmpl.Tags) > 0 {
+			title = append(title, "("+strings.Join(tmpl.Tags, ", ")+")")
+		}
+		if len(title) > 0 {
+			fmt.Printf("      %s\n", strings.Join(title, " "))
+		}
+		if tmpl.Description != "" {
+			fmt.Printf("      %s\n", tmpl.Description)
+		}
+		if tmpl.Owner != "" {
+			fmt.Printf("      Owner: %s\n", tmpl.Owner)
+		}
+	}
+	fmt.Println("\nUsage: --template <path>")
 }
 
 // displayRequiredVariables prints the list of required template variables
@@ -54,11 +206,21 @@ func displayRequiredVariables(vars []models.TemplateVariable) {
 // 3. Parse variables
 // 4. Collect user input
 // 5. Render template
-// 6. Send draft (via Outlook)
+// 6. Prepare recipients
+// 7. Post-process the draft
+// 8. Send draft
 func Run(sender mailer.EmailSender, options models.Options) error {
 	// 1. Scan templates
-	templatesDir := getTemplatesDir()
-	tmpls, err := templates.ScanTemplates(templatesDir)
+	dirs := getTemplatesDirs(options.TemplatesDirs)
+	tmpls, err := templates.ScanTemplates(dirs...)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Fresh install: fall back to the built-in templates until "mailmate init" creates a templates directory
+		if dir, builtinErr := builtinTemplatesDir(); builtinErr == nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\nUsing the built-in templates, run \"mailmate init\" to create your templates directory.\n\n", err)
+			dirs = []string{dir}
+			tmpls, err = templates.ScanTemplates(dirs...)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("scanning templates: %w", err)
 	}
@@ -69,25 +231,13 @@ func Run(sender mailer.EmailSender, options models.Options) error {
 		// --template flag was provided
 		if *options.Template == "" {
 			// --template flag provided but empty: list available templates and exit
-			fmt.Println("Available templates:")
-			for _, tmpl := range tmpls {
-				fmt.Printf("  - %s\n", tmpl.Path)
-			}
-			fmt.Println("\nUsage: --template <path>")
+			displayTemplates(tmpls)
 			return nil
 		}
 
 		// CLI template selection: find matching template by path
-		// Normalize paths for comparison (handles / vs \ on Windows)
-		normalizedInput := filepath.Clean(*options.Template)
-		found := false
-		for i := range tmpls {
-			if filepath.Clean(tmpls[i].Path) == normalizedInput {
-				selected = &tmpls[i]
-				found = true
-				break
-			}
-		}
+		var found bool
+		selected, found = findTemplate(tmpls, *options.Template)
 		if !found {
 			return fmt.Errorf("template not found: %s", *options.Template)
 		}
@@ -100,7 +250,7 @@ func Run(sender mailer.EmailSender, options models.Options) error {
 	}
 
 	// 3. Parse variables
-	vars, err := templates.ParseTemplate(selected.Path)
+	vars, err := templates.ParseTemplate(dirs, selected.Path)
 	if err != nil {
 		return fmt.Errorf("parsing template: %w", err)
 	}
@@ -142,7 +292,7 @@ func Run(sender mailer.EmailSender, options models.Options) error {
 	}
 
 	// Handle attachments
-	var attachments []string
+	var attachments []models.Attachment
 	for _, v := range vars {
 		for _, f := range v.Filters {
 			if f.Name == "type" && f.Arg == "filepath" {
@@ -152,47 +302,125 @@ func Run(sender mailer.EmailSender, options models.Options) error {
 					if err != nil {
 						return fmt.Errorf("resolving absolute path for %s: %w", fullPath, err)
 					}
-					attachments = append(attachments, absPath)
+					attachments = append(attachments, models.Attachment{Path: absPath})
 				}
 			}
 		}
 	}
 
+	// Add extra attachments from --attach flags, and from the TUI when running interactively
+	extra := options.Attachments
+	if options.KV == nil {
+		tuiAttachments, err := tui.CollectAttachments()
+		if err != nil {
+			return fmt.Errorf("collecting attachments: %w", err)
+		}
+		extra = append(append([]string{}, extra...), tuiAttachments...)
+	}
+
 	// 5. Render template
-	rendered, err := templates.RenderTemplate(selected.Path, input.Values)
+	rendered, err := templates.RenderTemplate(dirs, selected.Path, input.Values)
 	if err != nil {
 		return fmt.Errorf("rendering template: %w", err)
 	}
 
+	// Files attached by the template, then extra attachments
+	attachments, err = addAttachments(attachments, append(append([]string{}, rendered.Files...), extra...))
+	if err != nil {
+		return err
+	}
+
+	// Inline images referenced by the template
+	attachments = append(attachments, rendered.Attachments...)
+
 	// TODO: T019 - Implement preview screen here
 	// if !options.NoPreview { ... }
 
-	// 6. Prepare recipients with priority: CLI flags override template defaults
-	to := rendered.To
-	if options.To != "" {
-		to = options.To
+	// 6. Prepare recipients: --to/--cc/--bcc replace template defaults,
+	// --add-to/--add-cc/--add-bcc are merged with them
+	templateTo, templateCc, templateBcc := rendered.To, rendered.Cc, rendered.Bcc
+	if options.NoTemplateRecipients {
+		templateTo, templateCc, templateBcc = "", "", ""
+	}
+
+	to, err := resolveRecipients("to", templateTo, options.To, options.AddTo)
+	if err != nil {
+		return err
+	}
+
+	cc, err := resolveRecipients("cc", templateCc, options.Cc, options.AddCc)
+	if err != nil {
+		return err
 	}
 
-	cc := rendered.Cc
-	if options.Cc != "" {
-		cc = options.Cc
+	bcc, err := resolveRecipients("bcc", templateBcc, options.Bcc, options.AddBcc)
+	if err != nil {
+		return err
 	}
 
-	bcc := rendered.Bcc
-	if options.Bcc != "" {
-		bcc = options.Bcc
+	// The same person must not receive the email twice
+	to, cc, bcc = address.Dedupe(to, cc, bcc)
+
+	from, err := resolveFrom(rendered.From)
+	if err != nil {
+		return err
+	}
+
+	replyTo, err := address.Parse(rendered.ReplyTo)
+	if err != nil {
+		return fmt.Errorf("invalid %q addresses: %w", "reply_to", err)
 	}
 
-	// 7. Send draft
 	draft := models.DraftEmail{
-		To:          to,
-		Cc:          cc,
-		Bcc:         bcc,
-		Subject:     rendered.Subject,
-		HTMLBody:    rendered.HTML,
-		Attachments: attachments,
+		To:              to,
+		Cc:              cc,
+		Bcc:             bcc,
+		From:            from,
+		ReplyTo:         replyTo,
+		Subject:         rendered.Subject,
+		Importance:      rendered.Importance,
+		Sensitivity:     rendered.Sensitivity,
+		ReadReceipt:     rendered.ReadReceipt,
+		DeliveryReceipt: rendered.DeliveryReceipt,
+		Headers:         rendered.Headers,
+		HTMLBody:        rendered.HTML,
+		TextBody:        rendered.Text,
+		Attachments:     attachments,
+	}
+
+	// Meeting invitation: the recipients are invited, unless the template lists the attendees
+	if rendered.Event != nil {
+		event := *rendered.Event
+		if event.Organizer == nil {
+			event.Organizer = from
+		}
+		if len(event.Attendees) == 0 && len(event.OptionalAttendees) == 0 {
+			event.Attendees, event.OptionalAttendees = to, cc
+		}
+		draft.Event = &event
+	}
+
+	// 7. Post-process the draft: steps of the template, then of the configuration file
+	steps := append(append([]models.PostProcessStep{}, rendered.PostProcess...), options.PostProcess...)
+	pipeline, err := postprocess.New(steps)
+	if err != nil {
+		return err
+	}
+	templateDir, err := filepath.Abs(filepath.Dir(selected.Path))
+	if err != nil {
+		return fmt.Errorf("resolving template directory: %w", err)
+	}
+	templatesDirs := make([]string, len(dirs))
+	for i, dir := range dirs {
+		if templatesDirs[i], err = filepath.Abs(dir); err != nil {
+			return fmt.Errorf("resolving templates directory: %w", err)
+		}
+	}
+	if err := pipeline.Process(postprocess.Context{TemplateDir: templateDir, TemplatesDirs: templatesDirs}, &draft); err != nil {
+		return fmt.Errorf("post-processing draft: %w", err)
 	}
 
+	// 8. Send draft
 	if err := sender.Send(draft); err != nil {
 		return fmt.Errorf("sending draft: %w", err)
 	}
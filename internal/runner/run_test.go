@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"net/mail"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mailmate/internal/models"
+)
+
+func TestResolveRecipients(t *testing.T) {
+	tests := []struct {
+		name          string
+		templateValue string
+		replace       []string
+		add           []string
+		want          []mail.Address
+		wantErr       bool
+	}{
+		{
+			name:          "template only",
+			templateValue: "a@example.com; b@example.com",
+			want:          []mail.Address{{Address: "a@example.com"}, {Address: "b@example.com"}},
+		},
+		{
+			name:          "replace",
+			templateValue: "a@example.com",
+			replace:       []string{"c@example.com", "d@example.com"},
+			want:          []mail.Address{{Address: "c@example.com"}, {Address: "d@example.com"}},
+		},
+		{
+			name:          "add keeps template",
+			templateValue: "a@example.com",
+			add:           []string{"Carl <c@example.com>"},
+			want:          []mail.Address{{Address: "a@example.com"}, {Name: "Carl", Address: "c@example.com"}},
+		},
+		{
+			name:          "replace and add",
+			templateValue: "a@example.com",
+			replace:       []string{"b@example.com"},
+			add:           []string{"c@example.com"},
+			want:          []mail.Address{{Address: "b@example.com"}, {Address: "c@example.com"}},
+		},
+		{
+			name:    "invalid added address",
+			add:     []string{"nobody"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRecipients("to", tt.templateValue, tt.replace, tt.add)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveRecipients() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRecipients() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddAttachments(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.txt")
+	if err := os.WriteFile(small, []byte("hello"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	got, err := addAttachments([]models.Attachment{{Path: small}}, []string{small, small})
+	if err != nil {
+		t.Fatalf("addAttachments() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []models.Attachment{{Path: small}}) {
+		t.Errorf("addAttachments() = %v, want duplicates skipped", got)
+	}
+
+	if _, err := addAttachments(nil, []string{filepath.Join(dir, "missing.txt")}); err == nil {
+		t.Errorf("addAttachments() error = nil, want error for missing file")
+	}
+	if _, err := addAttachments(nil, []string{dir}); err == nil {
+		t.Errorf("addAttachments() error = nil, want error for directory")
+	}
+}
+
+func TestGetTemplatesDirs(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	sep := string(filepath.ListSeparator)
+
+	tests := []struct {
+		name       string
+		env        string
+		configured []string
+		want       []string
+	}{
+		{name: "default", want: []string{"templates"}},
+		{name: "config", configured: []string{"~/perso", "equipe"}, want: []string{filepath.Join(home, "perso"), "equipe"}},
+		{name: "home of another user", configured: []string{"~bob/templates"}, want: []string{"~bob/templates"}},
+		{name: "environment overrides config", env: "perso" + sep + " " + sep + "equipe", configured: []string{"autre"}, want: []string{"perso", "equipe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAILMATE_TEMPLATES_DIR", tt.env)
+			if got := getTemplatesDirs(tt.configured); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getTemplatesDirs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindTemplate(t *testing.T) {
+	tmpls := []models.TemplateRef{
+		{Name: "facture.html", Path: filepath.Join("perso", "relances", "facture.html"), Root: "perso", Category: "relances"},
+		{Name: "rapport.html", Path: filepath.Join("equipe", "rapport.html"), Root: "equipe"},
+	}
+
+	for name, want := range map[string]string{
+		"perso/relances/facture.html": "perso",
+		"relances/facture.html":       "perso",
+		"rapport.html":                "equipe",
+	} {
+		got, ok := findTemplate(tmpls, name)
+		if !ok || got.Root != want {
+			t.Errorf("findTemplate(%q) = %v, %v, want the template of %s", name, got, ok, want)
+		}
+	}
+	if _, ok := findTemplate(tmpls, "devis.html"); ok {
+		t.Errorf("findTemplate() found a missing template")
+	}
+}
@@ -0,0 +1,93 @@
+// Package starter holds the starter templates and configuration file created by "mailmate init".
+// The templates use a common layout (layouts/base.html) and partials, and are also used
+// as built-in templates when no templates directory exists.
+package starter
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates config.yaml
+var files embed.FS
+
+// configTemplate is the starter configuration file.
+var configTemplate = template.Must(template.ParseFS(files, "config.yaml"))
+
+// Templates returns the starter templates directory: templates, layouts and partials.
+func Templates() fs.FS {
+	sub, err := fs.Sub(files, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// WriteTemplates copies the starter templates into dir, creating it if needed.
+// Existing files are kept unless overwrite is set.
+// It returns the paths of the files written.
+func WriteTemplates(dir string, overwrite bool) ([]string, error) {
+	var written []string
+	err := fs.WalkDir(Templates(), ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if !overwrite {
+			if _, err := os.Stat(target); err == nil {
+				return nil
+			}
+		}
+
+		content, err := fs.ReadFile(Templates(), name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content, 0o644); err != nil {
+			return err
+		}
+		written = append(written, target)
+		return nil
+	})
+	if err != nil {
+		return written, fmt.Errorf("writing starter templates: %w", err)
+	}
+	return written, nil
+}
+
+// WriteConfig creates the starter configuration file at path, with templatesDir as templates directory.
+// An existing file is never overwritten: it returns false.
+func WriteConfig(path, templatesDir string) (bool, error) {
+	var b bytes.Buffer
+	// Single-quoted YAML scalars keep Windows backslashes as is
+	data := struct{ TemplatesDir string }{"'" + strings.ReplaceAll(templatesDir, "'", "''") + "'"}
+	if err := configTemplate.Execute(&b, data); err != nil {
+		return false, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return false, fmt.Errorf("creating config directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("creating config file: %w", err)
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return false, fmt.Errorf("writing config file: %w", err)
+	}
+	return true, f.Close()
+}
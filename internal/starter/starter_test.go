@@ -0,0 +1,101 @@
+package starter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mailmate/internal/config"
+	"mailmate/internal/templates"
+)
+
+func TestWriteTemplates(t *testing.T) {
+	dir := t.TempDir()
+	written, err := WriteTemplates(dir, false)
+	if err != nil {
+		t.Fatalf("WriteTemplates() error = %v", err)
+	}
+	if len(written) == 0 {
+		t.Fatalf("WriteTemplates() wrote no file")
+	}
+
+	tmpls, err := templates.ScanTemplates(dir)
+	if err != nil {
+		t.Fatalf("ScanTemplates() error = %v", err)
+	}
+	var names []string
+	for _, tmpl := range tmpls {
+		names = append(names, tmpl.Name)
+	}
+	if got, want := strings.Join(names, ","), "confirmation.html,invitation.html,rapport.html,relance.html"; got != want {
+		t.Errorf("starter templates = %s, want %s", got, want)
+	}
+
+	// Every starter template renders with sample values
+	report := filepath.Join(dir, "rapport.pdf")
+	if err := os.WriteFile(report, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+	for _, tmpl := range tmpls {
+		vars, err := templates.ParseTemplate([]string{dir}, tmpl.Path)
+		if err != nil {
+			t.Fatalf("ParseTemplate(%s) error = %v", tmpl.Name, err)
+		}
+		values := make(map[string]string)
+		for _, v := range vars {
+			values[v.Name] = "valeur"
+			for _, f := range v.Filters {
+				switch {
+				case f.Name == "type" && f.Arg == "date":
+					values[v.Name] = "17-10-2026"
+				case f.Name == "type" && f.Arg == "filepath":
+					values[v.Name] = report
+				case f.Name == "int":
+					values[v.Name] = "3"
+				}
+			}
+		}
+		values["ContactEmail"], values["Attendees"], values["MeetingTime"] = "jo@example.com", "jo@example.com", "14:00"
+
+		rendered, err := templates.RenderTemplate([]string{dir}, tmpl.Path, values)
+		if err != nil {
+			t.Fatalf("RenderTemplate(%s) error = %v", tmpl.Name, err)
+		}
+		if !strings.Contains(rendered.HTML, "Ma Société") || strings.Contains(rendered.HTML, "---") {
+			t.Errorf("RenderTemplate(%s) = %s, want the layout without frontmatter", tmpl.Name, rendered.HTML)
+		}
+	}
+
+	// Existing files are kept unless overwrite is set
+	if err := os.WriteFile(written[0], []byte("modifié"), 0o600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if again, err := WriteTemplates(dir, false); err != nil || len(again) != 0 {
+		t.Errorf("WriteTemplates() = %v, %v, want no file written", again, err)
+	}
+	if again, err := WriteTemplates(dir, true); err != nil || len(again) != len(written) {
+		t.Errorf("WriteTemplates(overwrite) = %v, %v, want every file written", again, err)
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mailmate", "config.yaml")
+	templatesDir := `C:\Users\Jo's\templates`
+
+	created, err := WriteConfig(path, templatesDir)
+	if err != nil || !created {
+		t.Fatalf("WriteConfig() = %v, %v, want the file created", created, err)
+	}
+	cfg, err := config.Load(path, true)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.TemplatesDirs) != 1 || cfg.TemplatesDirs[0] != templatesDir {
+		t.Errorf("TemplatesDirs = %q, want [%q]", cfg.TemplatesDirs, templatesDir)
+	}
+
+	if created, err := WriteConfig(path, "autre"); err != nil || created {
+		t.Errorf("WriteConfig() = %v, %v, want an existing file kept", created, err)
+	}
+}
@@ -0,0 +1,89 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"mailmate/internal/homedir"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+var (
+	// quotedRegex matches the string literals of a condition.
+	quotedRegex = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	// identifierRegex matches the PascalCase variables of a condition, but not attributes (Contact.Name).
+	identifierRegex = regexp.MustCompile(`(?:^|[^.\w])([A-Z][a-zA-Z0-9]*)`)
+)
+
+// conditionVariables returns the variables used by an attachment condition.
+func conditionVariables(when string) []string {
+	var names []string
+	for _, m := range identifierRegex.FindAllStringSubmatch(quotedRegex.ReplaceAllString(when, `""`), -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// evalCondition evaluates an attachment condition against the template variables.
+// An empty condition is true.
+func evalCondition(when string, ctx pongo2.Context) (bool, error) {
+	if strings.TrimSpace(when) == "" {
+		return true, nil
+	}
+	tpl, err := pongo2.FromString("{% if " + when + " %}true{% endif %}")
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q: %w", when, err)
+	}
+	out, err := tpl.Execute(ctx)
+	if err != nil {
+		return false, fmt.Errorf("evaluating condition %q: %w", when, err)
+	}
+	return out == "true", nil
+}
+
+// resolveAttachments returns the absolute paths of the files attached by the frontmatter.
+// Paths are rendered with the variables and resolved from baseDir. Glob patterns must match
+// at least one file, and plain paths must exist, so that a missing file is not silently left out.
+func resolveAttachments(attachments []TemplateAttachment, baseDir string, ctx pongo2.Context) ([]string, error) {
+	var paths []string
+	for _, a := range attachments {
+		ok, err := evalCondition(a.When, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		pattern, err := renderField(a.Path, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("rendering attachment path %q: %w", a.Path, err)
+		}
+		if expanded, err := homedir.Expand(pattern); err == nil {
+			pattern = expanded
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachment pattern %q: %w", a.Path, err)
+		}
+		found := false
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && !info.IsDir() {
+				paths = append(paths, m)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("attachment %q: no such file", a.Path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,107 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mailmate/internal/address"
+	"mailmate/internal/calendar"
+	"mailmate/internal/models"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// renderEvent renders the event of the frontmatter with the template variables.
+// The summary defaults to the rendered subject.
+func renderEvent(e *TemplateEvent, ctx pongo2.Context, subject string) (*models.Event, error) {
+	fields := map[string]string{
+		"uid": e.UID, "summary": e.Summary, "description": e.Description, "location": e.Location,
+		"start": e.Start, "end": e.End, "duration": e.Duration, "timezone": e.Timezone,
+		"organizer": e.Organizer, "attendees": string(e.Attendees), "optional_attendees": string(e.OptionalAttendees),
+	}
+	rendered := make(map[string]string, len(fields))
+	for name, value := range fields {
+		out, err := renderField(value, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render event '%s' field: %w", name, err)
+		}
+		rendered[name] = strings.TrimSpace(out)
+	}
+
+	event := &models.Event{
+		UID:         rendered["uid"],
+		Summary:     rendered["summary"],
+		Description: rendered["description"],
+		Location:    rendered["location"],
+	}
+	if event.UID == "" {
+		event.UID = calendar.NewUID()
+	}
+	if event.Summary == "" {
+		event.Summary = subject
+	}
+
+	loc, err := calendar.LoadLocation(rendered["timezone"])
+	if err != nil {
+		return nil, fmt.Errorf("event: %w", err)
+	}
+	if rendered["start"] == "" {
+		return nil, errors.New("event: start is required")
+	}
+	event.Start, event.AllDay, err = calendar.ParseTime(rendered["start"], loc)
+	if err != nil {
+		return nil, fmt.Errorf("event start: %w", err)
+	}
+
+	switch {
+	case rendered["end"] != "":
+		end, allDay, err := calendar.ParseTime(rendered["end"], loc)
+		if err != nil {
+			return nil, fmt.Errorf("event end: %w", err)
+		}
+		if allDay != event.AllDay {
+			return nil, errors.New("event: start and end must both be dates or both be date-times")
+		}
+		// The end date of an all-day event is exclusive
+		if allDay {
+			end = end.AddDate(0, 0, 1)
+		}
+		event.End = end
+	case rendered["duration"] != "":
+		if event.AllDay {
+			return nil, errors.New("event: duration cannot be used with an all-day event, set end instead")
+		}
+		d, err := time.ParseDuration(rendered["duration"])
+		if err != nil {
+			return nil, fmt.Errorf("event: invalid duration %q (e.g. 1h30m)", rendered["duration"])
+		}
+		event.End = event.Start.Add(d)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start.Add(time.Hour)
+	}
+	if !event.End.After(event.Start) {
+		return nil, errors.New("event: end must be after start")
+	}
+
+	if rendered["organizer"] != "" {
+		organizer, err := address.Parse(rendered["organizer"])
+		if err != nil {
+			return nil, fmt.Errorf("event organizer: %w", err)
+		}
+		if len(organizer) != 1 {
+			return nil, fmt.Errorf("event organizer: expected a single address, got %d", len(organizer))
+		}
+		event.Organizer = &organizer[0]
+	}
+	if event.Attendees, err = address.Parse(rendered["attendees"]); err != nil {
+		return nil, fmt.Errorf("event attendees: %w", err)
+	}
+	if event.OptionalAttendees, err = address.Parse(rendered["optional_attendees"]); err != nil {
+		return nil, fmt.Errorf("event optional attendees: %w", err)
+	}
+	return event, nil
+}
@@ -0,0 +1,77 @@
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mailmate/internal/models"
+)
+
+// reservedHeaders are the headers set by MailMate itself, which the "headers" frontmatter field cannot override.
+var reservedHeaders = []string{
+	"From", "Sender", "To", "Cc", "Bcc", "Reply-To", "Subject", "Date", "Message-Id", "Mime-Version",
+	"Importance", "X-Priority", "Sensitivity", "Disposition-Notification-To", "Return-Receipt-To", "X-Unsent",
+}
+
+// validateHeaderName checks that name is a valid custom header name (RFC 5322 field name).
+func validateHeaderName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty header name")
+	}
+	for _, c := range name {
+		if c <= ' ' || c > '~' || c == ':' {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	for _, reserved := range reservedHeaders {
+		if strings.EqualFold(name, reserved) {
+			return fmt.Errorf("header %q cannot be set in headers, use the dedicated field", name)
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(name), "content-") {
+		return fmt.Errorf("header %q cannot be set in headers", name)
+	}
+	return nil
+}
+
+// validateHeaderValue checks that a rendered header value fits on one line.
+func validateHeaderValue(name, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("header %q: value must not contain line breaks", name)
+	}
+	return nil
+}
+
+// parseImportance parses the rendered "importance" field. An empty value means the backend default.
+func parseImportance(value string) (models.Importance, error) {
+	switch i := models.Importance(strings.ToLower(strings.TrimSpace(value))); i {
+	case "", models.ImportanceLow, models.ImportanceNormal, models.ImportanceHigh:
+		return i, nil
+	default:
+		return "", fmt.Errorf("invalid importance %q (expected low, normal or high)", value)
+	}
+}
+
+// parseSensitivity parses the rendered "sensitivity" field. An empty value means the backend default.
+func parseSensitivity(value string) (models.Sensitivity, error) {
+	switch s := models.Sensitivity(strings.ToLower(strings.TrimSpace(value))); s {
+	case "", models.SensitivityNormal, models.SensitivityPersonal, models.SensitivityPrivate, models.SensitivityConfidential:
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid sensitivity %q (expected normal, personal, private or confidential)", value)
+	}
+}
+
+// parseFlag parses a rendered boolean field such as "read_receipt". An empty value is false.
+func parseFlag(field, value string) (bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q (expected true or false)", field, value)
+	}
+	return b, nil
+}
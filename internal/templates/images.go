@@ -0,0 +1,67 @@
+package templates
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"mailmate/internal/assets"
+	"mailmate/internal/models"
+)
+
+// imgSrcRegex matches the src attribute of an <img> tag.
+// The value is captured in group 2 (double quotes), 3 (single quotes) or 4 (unquoted).
+var imgSrcRegex = regexp.MustCompile(`(?is)<img\b[^>]*?\ssrc\s*=\s*("([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// cidUnsafeRegex matches the characters replaced when deriving a Content-ID from a file name.
+var cidUnsafeRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// EmbedImages finds the <img> tags of body referencing local files, attaches those files
+// as inline parts and rewrites their src to "cid:" URLs so that the images display for recipients.
+// Relative paths are resolved from the first of baseDirs holding the file: the directory of the template,
+// then the templates directories, where the layouts and partials find their images.
+// Remote (http, https...), data: and cid: URLs are left untouched.
+// An image referenced several times is attached once.
+func EmbedImages(body string, baseDirs ...string) (string, []models.Attachment, error) {
+	var out strings.Builder
+	var attachments []models.Attachment
+	cids := make(map[string]string)
+
+	last := 0
+	for _, m := range imgSrcRegex.FindAllStringSubmatchIndex(body, -1) {
+		// Find the group holding the value
+		start, end := m[4], m[5]
+		for g := 3; start == -1 && g <= 4; g++ {
+			start, end = m[2*g], m[2*g+1]
+		}
+
+		path, ok := assets.LocalPath(html.UnescapeString(body[start:end]), baseDirs)
+		if !ok {
+			continue
+		}
+
+		cid, seen := cids[path]
+		if !seen {
+			info, err := os.Stat(path)
+			if err != nil {
+				return "", nil, fmt.Errorf("image %q referenced by the template: %w", body[start:end], err)
+			}
+			if info.IsDir() {
+				return "", nil, fmt.Errorf("image %q referenced by the template is a directory", body[start:end])
+			}
+			cid = fmt.Sprintf("img%d.%s@mailmate", len(attachments)+1, cidUnsafeRegex.ReplaceAllString(filepath.Base(path), "_"))
+			cids[path] = cid
+			attachments = append(attachments, models.Attachment{Path: path, ContentID: cid})
+		}
+
+		out.WriteString(body[last:start])
+		out.WriteString("cid:" + cid)
+		last = end
+	}
+	out.WriteString(body[last:])
+
+	return out.String(), attachments, nil
+}
@@ -0,0 +1,82 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mailmate/internal/models"
+)
+
+func TestEmbedImages(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "assets"), 0o755); err != nil {
+		t.Fatalf("Failed to create assets dir: %v", err)
+	}
+	logo := filepath.Join(dir, "assets", "logo.png")
+	if err := os.WriteFile(logo, []byte("\x89PNG fake"), 0o600); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	shot := filepath.Join(dir, "assets", "my shot.jpg")
+	if err := os.WriteFile(shot, []byte("JPEG fake"), 0o600); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantBody string
+		want     []models.Attachment
+		wantErr  bool
+	}{
+		{
+			name:     "relative path",
+			body:     `<p><img alt="Logo" src="assets/logo.png"></p>`,
+			wantBody: `<p><img alt="Logo" src="cid:img1.logo.png@mailmate"></p>`,
+			want:     []models.Attachment{{Path: logo, ContentID: "img1.logo.png@mailmate"}},
+		},
+		{
+			name:     "same image twice, single quotes and escaped name",
+			body:     `<img src='assets/logo.png'><IMG SRC=assets/my%20shot.jpg /><img src="./assets/logo.png">`,
+			wantBody: `<img src='cid:img1.logo.png@mailmate'><IMG SRC=cid:img2.my_shot.jpg@mailmate /><img src="cid:img1.logo.png@mailmate">`,
+			want: []models.Attachment{
+				{Path: logo, ContentID: "img1.logo.png@mailmate"},
+				{Path: shot, ContentID: "img2.my_shot.jpg@mailmate"},
+			},
+		},
+		{
+			name:     "remote and embedded images untouched",
+			body:     `<img src="https://example.com/logo.png"><img src="//cdn.example.com/a.png"><img src="data:image/png;base64,AAAA"><img src="cid:x@y">`,
+			wantBody: `<img src="https://example.com/logo.png"><img src="//cdn.example.com/a.png"><img src="data:image/png;base64,AAAA"><img src="cid:x@y">`,
+		},
+		{
+			name:     "data-src is not src",
+			body:     `<img data-src="assets/logo.png">`,
+			wantBody: `<img data-src="assets/logo.png">`,
+		},
+		{
+			name:    "missing image",
+			body:    `<img src="assets/missing.png">`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, got, err := EmbedImages(tt.body, dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EmbedImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if body != tt.wantBody {
+				t.Errorf("EmbedImages() body = %q, want %q", body, tt.wantBody)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EmbedImages() attachments = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
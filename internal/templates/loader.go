@@ -0,0 +1,161 @@
+package templates
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// referenceRegex matches the files referenced by the {% extends %}, {% include %} and {% import %} tags.
+// The tag is captured in group 1, the file name in group 2 or 3.
+var referenceRegex = regexp.MustCompile(`\{%-?\s*(extends|include|import)\s+(?:"([^"]+)"|'([^']+)')`)
+
+// fileLoader loads the layouts, partials and macros referenced by a template.
+// Names are relative to the templates directories (e.g. "layouts/base.html"),
+// whatever the category of the template, and the frontmatter of the loaded files is stripped.
+// The directories form a search path: the file of the first directory holding it is used.
+type fileLoader struct {
+	roots []string
+}
+
+// Abs implements pongo2.TemplateLoader.
+// Names found in none of the directories resolve to the first one, for the error message.
+func (l *fileLoader) Abs(base, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	for _, root := range l.roots {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(l.roots[0], filepath.FromSlash(name))
+}
+
+// exists reports whether one of the templates directories holds the file name.
+func (l *fileLoader) exists(name string) bool {
+	_, err := os.Stat(l.Abs("", name))
+	return err == nil
+}
+
+// Get implements pongo2.TemplateLoader.
+// The {% text %} block of the layouts is removed: it is the text body of the templates
+// extending them (see inheritedText).
+func (l *fileLoader) Get(path string) (io.Reader, error) {
+	body, err := l.read(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, rest, ok := extractText(body); ok {
+		body = rest
+	}
+	return strings.NewReader(body), nil
+}
+
+// read returns the content of the file name, without its frontmatter.
+func (l *fileLoader) read(name string) (string, error) {
+	// Templates rendered from a string pass the names as is
+	content, err := os.ReadFile(l.Abs("", name))
+	if err != nil {
+		return "", err
+	}
+	_, body, err := splitFrontmatter(content)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return string(body), nil
+}
+
+// newTemplateSet returns the template set of the templates directories roots.
+func newTemplateSet(roots []string) *pongo2.TemplateSet {
+	return pongo2.NewSet("templates:"+strings.Join(roots, string(filepath.ListSeparator)), &fileLoader{roots: roots})
+}
+
+// referencedBodies returns the bodies of the layouts, and of the files referenced by body,
+// recursively, so that the variables of the layouts and partials are asked for too.
+// Missing files are left out: rendering reports them.
+func referencedBodies(roots []string, body string, layouts ...string) []string {
+	loader := &fileLoader{roots: roots}
+	seen := make(map[string]bool)
+	var bodies []string
+	var walk func(body string)
+	visit := func(name string) {
+		name = loader.Abs("", name)
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		content, err := loader.read(name)
+		if err != nil {
+			return
+		}
+		bodies = append(bodies, content)
+		walk(content)
+	}
+	walk = func(body string) {
+		for _, m := range referenceRegex.FindAllStringSubmatch(body, -1) {
+			visit(m[2] + m[3])
+		}
+	}
+	for _, layout := range layouts {
+		visit(layout)
+	}
+	walk(body)
+	return bodies
+}
+
+// inheritedText returns the {% text %} block of the nearest layout body extends, directly or
+// through other layouts, or "" if none has one. The layouts wrapping the body (Markdown templates)
+// come after the layouts it extends. A text block in a file included or imported by the template
+// or its layouts would not be used, and is reported.
+func inheritedText(roots []string, body string, layouts ...string) (string, error) {
+	loader := &fileLoader{roots: roots}
+	seen := make(map[string]bool)
+	text := ""
+	var walk func(body string, layout bool) error
+	visit := func(name string, layout bool) error {
+		path := loader.Abs("", name)
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		// Missing files are reported by rendering
+		content, err := loader.read(path)
+		if err != nil {
+			return nil
+		}
+		if t, _, ok := extractText(content); ok {
+			if !layout {
+				return fmt.Errorf("%s: a {%% text %%} block is only used in a template or its layouts, not in an included file", name)
+			}
+			if text == "" {
+				text = t
+			}
+		}
+		return walk(content, layout)
+	}
+	walk = func(body string, layout bool) error {
+		for _, m := range referenceRegex.FindAllStringSubmatch(body, -1) {
+			if err := visit(m[2]+m[3], layout && m[1] == "extends"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(body, true); err != nil {
+		return "", err
+	}
+	for _, layout := range layouts {
+		if err := visit(layout, true); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,99 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// DefaultMarkdownLayout is the layout of the Markdown templates without a "layout" frontmatter field,
+// relative to the templates directory. Without this file, a minimal HTML page is used.
+const DefaultMarkdownLayout = "layouts/markdown.html"
+
+// builtinMarkdownLayout wraps the Markdown templates when the templates directory has no layout.
+const builtinMarkdownLayout = `<html>
+<head>
+<meta charset="utf-8">
+<style>
+    body { font-family: Arial, sans-serif; font-size: 14px; color: #333333; }
+    table { border-collapse: collapse; }
+    th, td { border: 1px solid #dddddd; padding: 4px 8px; text-align: left; }
+    th { background-color: #f2f2f2; }
+</style>
+</head>
+<body>
+{{ content }}
+</body>
+</html>
+`
+
+// markdown converts Markdown to HTML with the GitHub extensions (tables, strikethrough, autolinks, task lists).
+// Line breaks are kept, as in an email, and raw HTML is allowed.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithHardWraps(), html.WithUnsafe()),
+)
+
+// IsMarkdown reports whether the template at path is a Markdown template (.md).
+func IsMarkdown(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".md")
+}
+
+// markdownLayout returns the name of the layout wrapping a Markdown template, relative to
+// the templates directories roots, or "" for the built-in layout.
+func markdownLayout(roots []string, layout string) string {
+	if layout != "" {
+		return layout
+	}
+	if (&fileLoader{roots: roots}).exists(DefaultMarkdownLayout) {
+		return DefaultMarkdownLayout
+	}
+	return ""
+}
+
+// wrappingLayouts returns the layout wrapping the template at path, as a list: the layout
+// of a Markdown template, unless it is the built-in one, and none for an HTML template.
+func wrappingLayouts(roots []string, path, layout string) []string {
+	if !IsMarkdown(path) {
+		return nil
+	}
+	if name := markdownLayout(roots, layout); name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+// renderMarkdown converts the rendered Markdown body to HTML and wraps it in its layout.
+// The layout receives the HTML in the "content" variable, along with the template variables.
+func renderMarkdown(set *pongo2.TemplateSet, roots []string, layout, body string, ctx pongo2.Context) (string, error) {
+	var content bytes.Buffer
+	if err := markdown.Convert([]byte(body), &content); err != nil {
+		return "", fmt.Errorf("converting markdown: %w", err)
+	}
+
+	var tpl *pongo2.Template
+	var err error
+	if name := markdownLayout(roots, layout); name != "" {
+		tpl, err = set.FromFile(name)
+	} else {
+		tpl, err = set.FromString(builtinMarkdownLayout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("parsing layout: %w", err)
+	}
+
+	layoutCtx := pongo2.Context{}
+	layoutCtx.Update(ctx)
+	layoutCtx["content"] = pongo2.AsSafeValue(content.String())
+	out, err := tpl.Execute(layoutCtx)
+	if err != nil {
+		return "", fmt.Errorf("rendering layout: %w", err)
+	}
+	return out, nil
+}
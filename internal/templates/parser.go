@@ -3,37 +3,121 @@ package templates
 import (
 	"bytes"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 	"mailmate/internal/models"
+	"mailmate/internal/postprocess"
 )
 
+// textBlockRegex matches the {% text %}...{% endtext %} block holding the hand-written text body.
+var textBlockRegex = regexp.MustCompile(`(?s)\{%-?\s*text\s*-?%\}\r?\n?(.*?)\{%-?\s*endtext\s*-?%\}\r?\n?`)
+
 // ParsedTemplateFile represents the separated subject and body from a template file.
 type ParsedTemplateFile struct {
+	// Metadata describing the template in the picker
+	Title       string
+	Description string
+	Tags        []string
+	Owner       string
+	Language    string
+
 	Subject string
 	Body    string
+	// Layout is the HTML layout wrapping a Markdown body, relative to the templates directory.
+	Layout string
+	// Text is the hand-written plain-text body, if the template provides one.
+	Text string
+	// PostProcess are the post-processing steps requested by the frontmatter.
+	PostProcess []models.PostProcessStep
 	// Default recipients from template frontmatter
 	To  string
 	Cc  string
 	Bcc string
+	// Sender and reply settings from template frontmatter
+	From    string
+	ReplyTo string
+	// Message options from template frontmatter, rendered and validated by RenderTemplate
+	Importance      string
+	Sensitivity     string
+	ReadReceipt     string
+	DeliveryReceipt string
+	// Headers are the custom headers from template frontmatter, keyed by header name.
+	Headers map[string]string
+	// Attachments are the files attached by the template frontmatter.
+	Attachments []TemplateAttachment
+	// Event is the meeting the template invites to, if any.
+	Event *TemplateEvent
 }
 
-// ParseTemplateFile reads a template file, extracts the frontmatter (if any),
-// and returns the parsed subject and body.
-func ParseTemplateFile(path string) (*ParsedTemplateFile, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+// TemplateEvent is the meeting described by the "event" frontmatter field.
+// Every field may contain variables.
+type TemplateEvent struct {
+	// UID identifies the meeting. Defaults to a random UID.
+	UID string `yaml:"uid"`
+	// Summary is the title of the meeting. Defaults to the subject.
+	Summary     string `yaml:"summary"`
+	Description string `yaml:"description"`
+	Location    string `yaml:"location"`
+	// Start and End are date-times (DD-MM-YYYY HH:MM), or dates for an all-day event.
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	// Duration is used when End is empty (e.g. "1h30m"). Defaults to one hour, or one day for an all-day event.
+	Duration string `yaml:"duration"`
+	// Timezone is the time zone of Start and End (e.g. "Europe/Paris"). Defaults to the local time zone.
+	Timezone  string         `yaml:"timezone"`
+	Organizer string         `yaml:"organizer"`
+	Attendees recipientField `yaml:"attendees"`
+	// OptionalAttendees are the optional participants.
+	OptionalAttendees recipientField `yaml:"optional_attendees"`
+}
+
+// fields returns the fields of the event, for variable scanning.
+func (e *TemplateEvent) fields() []string {
+	return []string{
+		e.UID, e.Summary, e.Description, e.Location, e.Start, e.End, e.Duration, e.Timezone,
+		e.Organizer, string(e.Attendees), string(e.OptionalAttendees),
 	}
+}
+
+// TemplateAttachment is a file attached by the "attachments" frontmatter field.
+type TemplateAttachment struct {
+	// Path is the file path or glob pattern, relative to the template. It may contain variables.
+	Path string `yaml:"path"`
+	// When is an optional condition on the variables (e.g. `Country == "FR"`).
+	// The file is attached only if it is true.
+	When string `yaml:"when"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler. An attachment is either a path, or a mapping with a path and a condition.
+func (a *TemplateAttachment) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*a = TemplateAttachment{Path: node.Value}
+		return nil
+	}
+	type plain TemplateAttachment
+	var p plain
+	if err := node.Decode(&p); err != nil {
+		return err
+	}
+	if p.Path == "" {
+		return fmt.Errorf("line %d: attachment without a path", node.Line)
+	}
+	*a = TemplateAttachment(p)
+	return nil
+}
 
+// splitFrontmatter separates the YAML frontmatter of a template from its body.
+// The frontmatter is nil if the template has none.
+func splitFrontmatter(content []byte) (yamlData, body []byte, err error) {
 	// Check for frontmatter start
 	if !bytes.HasPrefix(content, []byte("---")) {
-		return &ParsedTemplateFile{
-			Body: string(content),
-		}, nil
+		return nil, content, nil
 	}
 
 	// Find the end of the frontmatter
@@ -49,11 +133,9 @@ func ParseTemplateFile(path string) (*ParsedTemplateFile, error) {
 
 	if endIndex == -1 {
 		// Started with --- but no closing --- found.
-		return nil, fmt.Errorf("malformed frontmatter: missing closing '---'")
+		return nil, nil, fmt.Errorf("malformed frontmatter: missing closing '---'")
 	}
 
-	yamlData := content[offset:endIndex]
-
 	// Body starts after the closing ---
 	// The closing sequence is \n--- or \r\n---. We need to skip that plus potentially the newline after it.
 	bodyStart := endIndex + 4 // \n--- is 4 chars
@@ -69,35 +151,212 @@ func ParseTemplateFile(path string) (*ParsedTemplateFile, error) {
 		bodyStart++
 	}
 
+	return content[offset:endIndex], content[bodyStart:], nil
+}
+
+// ParseTemplateFile reads a template file, extracts the frontmatter (if any),
+// and returns the parsed subject and body.
+//
+// The plain-text body is taken from a {% text %}...{% endtext %} block of the template,
+// which is removed from the HTML body, or else from a sibling .txt file with the same base name
+// (relance.html → relance.txt).
+func ParseTemplateFile(path string) (*ParsedTemplateFile, error) {
+	parsed, err := parseFrontmatter(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if text, rest, ok := extractText(parsed.Body); ok {
+		parsed.Text, parsed.Body = text, rest
+		return parsed, nil
+	}
+
+	text, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".txt")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading text body: %w", err)
+	}
+	parsed.Text = string(text)
+	return parsed, nil
+}
+
+// extractText returns the content of the {% text %} block of body, and body without it,
+// or false if body has no text block.
+func extractText(body string) (text, rest string, ok bool) {
+	m := textBlockRegex.FindStringSubmatchIndex(body)
+	if m == nil {
+		return "", body, false
+	}
+	return body[m[2]:m[3]], body[:m[0]] + body[m[1]:], true
+}
+
+// parseFrontmatter reads a template file and separates the frontmatter fields from the body.
+func parseFrontmatter(path string) (*ParsedTemplateFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlData, body, err := splitFrontmatter(content)
+	if err != nil {
+		return nil, err
+	}
+	if yamlData == nil {
+		return &ParsedTemplateFile{
+			Body: string(body),
+		}, nil
+	}
+
 	var meta struct {
-		Subject string `yaml:"subject"`
-		To      string `yaml:"to"`
-		Cc      string `yaml:"cc"`
-		Bcc     string `yaml:"bcc"`
+		Title       string    `yaml:"title"`
+		Description string    `yaml:"description"`
+		Tags        listField `yaml:"tags"`
+		Owner       string    `yaml:"owner"`
+		Language    string    `yaml:"language"`
+
+		Subject string         `yaml:"subject"`
+		Layout  string         `yaml:"layout"`
+		To      recipientField `yaml:"to"`
+		Cc      recipientField `yaml:"cc"`
+		Bcc     recipientField `yaml:"bcc"`
+		From    string         `yaml:"from"`
+		ReplyTo recipientField `yaml:"reply_to"`
+		// Scalars are kept as strings, as they may contain variables
+		Importance      string               `yaml:"importance"`
+		Sensitivity     string               `yaml:"sensitivity"`
+		ReadReceipt     string               `yaml:"read_receipt"`
+		DeliveryReceipt string               `yaml:"delivery_receipt"`
+		Headers         map[string]string    `yaml:"headers"`
+		Attachments     []TemplateAttachment `yaml:"attachments"`
+		Event           *TemplateEvent       `yaml:"event"`
+		// InlineCSS is a shorthand for the "inline_css" post-processing step.
+		InlineCSS   bool              `yaml:"inline_css"`
+		PostProcess postprocess.Steps `yaml:"postprocess"`
 	}
 	if err := yaml.Unmarshal(yamlData, &meta); err != nil {
 		return nil, fmt.Errorf("parsing frontmatter yaml: %w", err)
 	}
+	for name := range meta.Headers {
+		if err := validateHeaderName(name); err != nil {
+			return nil, fmt.Errorf("invalid frontmatter headers: %w", err)
+		}
+	}
 
 	return &ParsedTemplateFile{
-		Subject: meta.Subject,
-		Body:    string(content[bodyStart:]),
-		To:      meta.To,
-		Cc:      meta.Cc,
-		Bcc:     meta.Bcc,
+		Title:           meta.Title,
+		Description:     meta.Description,
+		Tags:            meta.Tags,
+		Owner:           meta.Owner,
+		Language:        meta.Language,
+		Subject:         meta.Subject,
+		Layout:          meta.Layout,
+		Body:            string(body),
+		To:              string(meta.To),
+		Cc:              string(meta.Cc),
+		Bcc:             string(meta.Bcc),
+		From:            meta.From,
+		ReplyTo:         string(meta.ReplyTo),
+		Importance:      meta.Importance,
+		Sensitivity:     meta.Sensitivity,
+		ReadReceipt:     meta.ReadReceipt,
+		DeliveryReceipt: meta.DeliveryReceipt,
+		Headers:         meta.Headers,
+		Attachments:     meta.Attachments,
+		Event:           meta.Event,
+		PostProcess:     postProcessSteps(meta.PostProcess, meta.InlineCSS),
 	}, nil
 }
 
+// postProcessSteps returns the post-processing steps of the frontmatter.
+// With inline_css, the CSS inliner runs first unless the steps already list it.
+func postProcessSteps(steps postprocess.Steps, inlineCSS bool) []models.PostProcessStep {
+	if inlineCSS && !slices.ContainsFunc(steps, func(s models.PostProcessStep) bool { return s.Name == "inline_css" }) {
+		steps = append(postprocess.Steps{{Name: "inline_css"}}, steps...)
+	}
+	return steps
+}
+
+// recipientField is a frontmatter recipient field. It accepts either a single string
+// ("a@example.com; b@example.com") or a YAML list of addresses, which is joined with commas.
+type recipientField string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (f *recipientField) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		*f = recipientField(strings.Join(list, ", "))
+		return nil
+	}
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	*f = recipientField(s)
+	return nil
+}
+
+// listField is a frontmatter list field. It accepts either a YAML list
+// or a single comma separated string ("relance, compta").
+type listField []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (f *listField) UnmarshalYAML(node *yaml.Node) error {
+	var list []string
+	if node.Kind == yaml.SequenceNode {
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+	} else {
+		var s string
+		if err := node.Decode(&s); err != nil {
+			return err
+		}
+		list = strings.Split(s, ",")
+	}
+
+	*f = nil
+	for _, item := range list {
+		if item = strings.TrimSpace(item); item != "" {
+			*f = append(*f, item)
+		}
+	}
+	return nil
+}
+
 // ParseTemplate reads a template file and extracts variables and their filters.
-// It parses both the frontmatter Subject and the Body.
-func ParseTemplate(path string) ([]models.TemplateVariable, error) {
+// It parses both the frontmatter Subject and the Body, as well as the layouts and partials
+// the body references, which are looked up in the templates directories dirs (see RenderTemplate).
+func ParseTemplate(dirs []string, path string) ([]models.TemplateVariable, error) {
 	parsed, err := ParseTemplateFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	// Combine subject, recipients, and body for variable scanning
-	combined := parsed.Subject + "\n" + parsed.To + "\n" + parsed.Cc + "\n" + parsed.Bcc + "\n" + parsed.Body
+	// Combine subject, recipients, headers and body for variable scanning
+	fields := []string{
+		parsed.Subject, parsed.To, parsed.Cc, parsed.Bcc, parsed.From, parsed.ReplyTo,
+		parsed.Importance, parsed.Sensitivity, parsed.ReadReceipt, parsed.DeliveryReceipt,
+	}
+	for _, name := range slices.Sorted(maps.Keys(parsed.Headers)) {
+		fields = append(fields, parsed.Headers[name])
+	}
+	for _, a := range parsed.Attachments {
+		fields = append(fields, a.Path)
+	}
+	if parsed.Event != nil {
+		fields = append(fields, parsed.Event.fields()...)
+	}
+	fields = append(fields, parsed.Body, parsed.Text)
+	roots := templatesRoots(dirs, path)
+	layouts := wrappingLayouts(roots, path, parsed.Layout)
+	// The text block of the layouts is scanned with them, but an unused one is reported now
+	if _, err := inheritedText(roots, parsed.Body, layouts...); err != nil {
+		return nil, err
+	}
+	fields = append(fields, referencedBodies(roots, parsed.Body, layouts...)...)
+	combined := strings.Join(fields, "\n")
 
 	// Regex to find {{ VariableName | filters... }}
 	// Captures:
@@ -131,6 +390,16 @@ func ParseTemplate(path string) ([]models.TemplateVariable, error) {
 		variables = append(variables, tv)
 	}
 
+	// Attachment conditions use plain variable names (e.g. `Country == "FR"`)
+	for _, a := range parsed.Attachments {
+		for _, name := range conditionVariables(a.When) {
+			if !seen[name] {
+				seen[name] = true
+				variables = append(variables, models.TemplateVariable{Name: name})
+			}
+		}
+	}
+
 	return variables, nil
 }
 
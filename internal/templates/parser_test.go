@@ -0,0 +1,691 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mailmate/internal/models"
+)
+
+func TestParseTemplateFileText(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		sibling  string
+		wantBody string
+		wantText string
+	}{
+		{
+			name:     "text block",
+			template: "---\nsubject: Relance\n---\n<p>Bonjour {{ Name }}</p>\n{% text %}\nBonjour {{ Name }}\n{% endtext %}\n",
+			wantBody: "<p>Bonjour {{ Name }}</p>\n",
+			wantText: "Bonjour {{ Name }}\n",
+		},
+		{
+			name:     "sibling text file",
+			template: "<p>Bonjour {{ Name }}</p>\n",
+			sibling:  "Bonjour {{ Name }}\n",
+			wantBody: "<p>Bonjour {{ Name }}</p>\n",
+			wantText: "Bonjour {{ Name }}\n",
+		},
+		{
+			name:     "text block takes precedence over the sibling file",
+			template: "<p>HTML</p>{%- text -%}Bloc{%- endtext -%}",
+			sibling:  "Fichier",
+			wantBody: "<p>HTML</p>",
+			wantText: "Bloc",
+		},
+		{
+			name:     "no text body",
+			template: "<p>HTML</p>",
+			wantBody: "<p>HTML</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "relance.html")
+			if err := os.WriteFile(path, []byte(tt.template), 0o600); err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+			if tt.sibling != "" {
+				if err := os.WriteFile(filepath.Join(dir, "relance.txt"), []byte(tt.sibling), 0o600); err != nil {
+					t.Fatalf("Failed to write text file: %v", err)
+				}
+			}
+
+			parsed, err := ParseTemplateFile(path)
+			if err != nil {
+				t.Fatalf("ParseTemplateFile() error = %v", err)
+			}
+			if parsed.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", parsed.Body, tt.wantBody)
+			}
+			if parsed.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", parsed.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateText(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "relance.html")
+	content := "<p>Bonjour {{ Name }}</p>\n{% text %}\nBonjour {{ Name }} & {{ Other }}\n{% endtext %}\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	vars, err := ParseTemplate([]string{dir}, path)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if len(vars) != 2 || vars[1].Name != "Other" {
+		t.Errorf("ParseTemplate() = %v, want variables of the text body too", vars)
+	}
+
+	rendered, err := RenderTemplate([]string{dir}, path, map[string]string{"Name": "Jo & Co", "Other": "<b>"})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	if rendered.HTML != "<p>Bonjour Jo &amp; Co</p>\n" {
+		t.Errorf("HTML = %q", rendered.HTML)
+	}
+	if rendered.Text != "Bonjour Jo & Co & <b>\n" {
+		t.Errorf("Text = %q, want unescaped values", rendered.Text)
+	}
+}
+
+func TestRenderTemplateInheritedText(t *testing.T) {
+	layout := "<main>{% block content %}{% endblock %}</main>{% text %}\n-- \n{{ Signature }}\n{% endtext %}\n"
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantText string
+		wantErr  bool
+	}{
+		{
+			name: "text block of the layout",
+			files: map[string]string{
+				"layouts/base.html": layout,
+				"relance.html":      "{% extends \"layouts/base.html\" %}{% block content %}<p>Bonjour {{ Name }}</p>{% endblock %}",
+			},
+			wantText: "-- \nL'équipe\n",
+		},
+		{
+			name: "text block of an intermediate layout",
+			files: map[string]string{
+				"layouts/base.html":   layout,
+				"layouts/client.html": "{% extends \"layouts/base.html\" %}{% block content %}<p>Bonjour {{ Name }}</p>{% endblock %}{% text %}Bonjour {{ Name }}{% endtext %}",
+				"relance.html":        "{% extends \"layouts/client.html\" %}",
+			},
+			wantText: "Bonjour Jo & Co",
+		},
+		{
+			name: "text block of the template nested in a block",
+			files: map[string]string{
+				"layouts/base.html": layout,
+				"relance.html":      "{% extends \"layouts/base.html\" %}{% block content %}<p>Bonjour {{ Name }}</p>{% text %}Bonjour {{ Name }}{% endtext %}{% endblock %}",
+			},
+			wantText: "Bonjour Jo & Co",
+		},
+		{
+			name: "text block of an included file",
+			files: map[string]string{
+				"partials/footer.html": "<footer></footer>{% text %}Signature{% endtext %}",
+				"relance.html":         "<p>Bonjour {{ Name }}</p>{% include \"partials/footer.html\" %}",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+			path := filepath.Join(dir, "relance.html")
+
+			vars, err := ParseTemplate([]string{dir}, path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseTemplate() error = nil, want error")
+				}
+				if _, err := RenderTemplate([]string{dir}, path, nil); err == nil {
+					t.Errorf("RenderTemplate() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTemplate() error = %v", err)
+			}
+			if want := []models.TemplateVariable{{Name: "Name"}, {Name: "Signature"}}; !reflect.DeepEqual(vars, want) {
+				t.Errorf("ParseTemplate() = %v, want %v, with the variables of the layout text block", vars, want)
+			}
+
+			rendered, err := RenderTemplate([]string{dir}, path, map[string]string{"Name": "Jo & Co", "Signature": "L'équipe"})
+			if err != nil {
+				t.Fatalf("RenderTemplate() error = %v", err)
+			}
+			if rendered.HTML != "<main><p>Bonjour Jo &amp; Co</p></main>" {
+				t.Errorf("HTML = %q", rendered.HTML)
+			}
+			if rendered.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", rendered.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestParseTemplateFilePostProcess(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     []models.PostProcessStep
+	}{
+		{
+			name:     "inline_css shorthand",
+			template: "---\ninline_css: true\npostprocess:\n  - minify\n---\n<p>HTML</p>",
+			want:     []models.PostProcessStep{{Name: "inline_css"}, {Name: "minify"}},
+		},
+		{
+			name:     "inline_css already listed",
+			template: "---\ninline_css: true\npostprocess:\n  - minify\n  - inline_css\n---\n<p>HTML</p>",
+			want:     []models.PostProcessStep{{Name: "minify"}, {Name: "inline_css"}},
+		},
+		{
+			name:     "options",
+			template: "---\npostprocess:\n  - name: signature\n    file: signature.html\n---\n<p>HTML</p>",
+			want:     []models.PostProcessStep{{Name: "signature", Options: map[string]any{"file": "signature.html"}}},
+		},
+		{
+			name:     "no post-processing",
+			template: "<p>HTML</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "relance.html")
+			if err := os.WriteFile(path, []byte(tt.template), 0o600); err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+
+			parsed, err := ParseTemplateFile(path)
+			if err != nil {
+				t.Fatalf("ParseTemplateFile() error = %v", err)
+			}
+			if len(parsed.PostProcess) != 0 || len(tt.want) != 0 {
+				if !reflect.DeepEqual(parsed.PostProcess, tt.want) {
+					t.Errorf("PostProcess = %+v, want %+v", parsed.PostProcess, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderTemplateHeaders(t *testing.T) {
+	template := `---
+subject: Relance
+from: "{{ Team }} <support@example.com>"
+reply_to: [tickets@example.com, "{{ Contact }}"]
+importance: "{{ Priority }}"
+sensitivity: Confidential
+read_receipt: true
+delivery_receipt: "{{ Receipt }}"
+headers:
+  X-Campaign: "relance-{{ Year }}"
+---
+<p>Bonjour</p>`
+	path := filepath.Join(t.TempDir(), "relance.html")
+	if err := os.WriteFile(path, []byte(template), 0o600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	vars, err := ParseTemplate(nil, path)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if len(vars) != 5 {
+		t.Errorf("ParseTemplate() = %v, want the variables of every field", vars)
+	}
+
+	values := map[string]string{"Team": "Support", "Contact": "jo@example.com", "Priority": "High", "Receipt": "false", "Year": "2026"}
+	rendered, err := RenderTemplate(nil, path, values)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	want := &models.RenderedTemplate{
+		Subject:     "Relance",
+		HTML:        "<p>Bonjour</p>",
+		Text:        rendered.Text,
+		From:        "Support <support@example.com>",
+		ReplyTo:     "tickets@example.com, jo@example.com",
+		Importance:  models.ImportanceHigh,
+		Sensitivity: models.SensitivityConfidential,
+		ReadReceipt: true,
+		Headers:     map[string]string{"X-Campaign": "relance-2026"},
+	}
+	if !reflect.DeepEqual(rendered, want) {
+		t.Errorf("RenderTemplate() = %+v, want %+v", rendered, want)
+	}
+
+	values["Priority"] = "urgent"
+	if _, err := RenderTemplate(nil, path, values); err == nil {
+		t.Errorf("RenderTemplate() error = nil, want an error for an invalid importance")
+	}
+}
+
+func TestRenderTemplateFieldsNotEscaped(t *testing.T) {
+	template := `---
+subject: "{{ Project }}"
+to: '"{{ Name }}" <j@example.com>'
+headers:
+  X-Project: "{{ Project }}"
+---
+<p>{{ Project }}</p>`
+	path := filepath.Join(t.TempDir(), "relance.html")
+	if err := os.WriteFile(path, []byte(template), 0o600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	rendered, err := RenderTemplate(nil, path, map[string]string{"Project": "R&D <O'Brien>", "Name": "Doe, J & O'Brien"})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	if rendered.Subject != "R&D <O'Brien>" {
+		t.Errorf("Subject = %q, want the raw value", rendered.Subject)
+	}
+	if want := `"Doe, J & O'Brien" <j@example.com>`; rendered.To != want {
+		t.Errorf("To = %q, want %q", rendered.To, want)
+	}
+	if got := rendered.Headers["X-Project"]; got != "R&D <O'Brien>" {
+		t.Errorf("X-Project = %q, want the raw value", got)
+	}
+	if rendered.HTML != "<p>R&amp;D &lt;O&#39;Brien&gt;</p>" {
+		t.Errorf("HTML = %q, want escaped values", rendered.HTML)
+	}
+}
+
+func TestParseTemplateFileInvalidHeaders(t *testing.T) {
+	for _, name := range []string{"Subject", "content-type", "X Bad", "X:Bad"} {
+		path := filepath.Join(t.TempDir(), "relance.html")
+		content := "---\nheaders:\n  \"" + name + "\": value\n---\n<p>HTML</p>"
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+		if _, err := ParseTemplateFile(path); err == nil {
+			t.Errorf("ParseTemplateFile() error = nil for header %q, want error", name)
+		}
+	}
+}
+
+func TestRenderTemplateAttachments(t *testing.T) {
+	dir := t.TempDir()
+	files := make(map[string]string)
+	for _, name := range []string{"cgv.pdf", "tarifs/fr.pdf", "tarifs/en.pdf", "annexes/a.pdf", "annexes/b.pdf", "Q&A.pdf"} {
+		files[name] = "%PDF"
+	}
+	writeTree(t, dir, files)
+
+	tests := []struct {
+		name    string
+		fields  string
+		values  map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "static file",
+			fields: "attachments:\n  - cgv.pdf\n",
+			want:   []string{"cgv.pdf"},
+		},
+		{
+			name:   "templated path and glob",
+			fields: "attachments:\n  - \"tarifs/{{ Lang }}.pdf\"\n  - annexes/*.pdf\n",
+			values: map[string]string{"Lang": "fr"},
+			want:   []string{"tarifs/fr.pdf", "annexes/a.pdf", "annexes/b.pdf"},
+		},
+		{
+			name:   "templated path with special characters",
+			fields: "attachments:\n  - \"{{ Document }}\"\n",
+			values: map[string]string{"Document": "Q&A.pdf"},
+			want:   []string{"Q&A.pdf"},
+		},
+		{
+			name:   "conditions",
+			fields: "attachments:\n  - path: cgv.pdf\n    when: Country == \"FR\" and Client != \"ACME\"\n  - path: tarifs/en.pdf\n    when: Country != \"FR\"\n",
+			values: map[string]string{"Country": "FR", "Client": "Dupont"},
+			want:   []string{"cgv.pdf"},
+		},
+		{
+			name:    "missing file",
+			fields:  "attachments:\n  - conditions.pdf\n",
+			wantErr: true,
+		},
+		{
+			name:    "glob without match",
+			fields:  "attachments:\n  - \"*.docx\"\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "offre.html")
+			if err := os.WriteFile(path, []byte("---\n"+tt.fields+"---\n<p>Offre</p>"), 0o600); err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+
+			rendered, err := RenderTemplate([]string{dir}, path, tt.values)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RenderTemplate() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplate() error = %v", err)
+			}
+
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(dir, name))
+			}
+			if !reflect.DeepEqual(rendered.Files, want) {
+				t.Errorf("Files = %v, want %v", rendered.Files, want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateConditionVariables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "offre.html")
+	content := "---\nattachments:\n  - path: cgv.pdf\n    when: Country == \"FR\" and not Contact.Vip and Lang in 'Fr En'\n---\n<p>{{ Country }}</p>"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	vars, err := ParseTemplate(nil, path)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	var names []string
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	if want := []string{"Country", "Contact", "Lang"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("variables = %v, want %v", names, want)
+	}
+}
+
+func TestRenderTemplateEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     string
+		values    map[string]string
+		wantStart string
+		wantEnd   string
+		wantAll   bool
+		wantErr   bool
+	}{
+		{
+			name:      "start and end in a time zone",
+			event:     "  start: \"{{ Date }} 14:00\"\n  end: \"{{ Date }} 15:30\"\n  timezone: Europe/Paris\n",
+			values:    map[string]string{"Date": "17-10-2026"},
+			wantStart: "2026-10-17T12:00:00Z",
+			wantEnd:   "2026-10-17T13:30:00Z",
+		},
+		{
+			name:      "duration",
+			event:     "  start: 2026-10-17T09:00:00+02:00\n  duration: 45m\n",
+			wantStart: "2026-10-17T07:00:00Z",
+			wantEnd:   "2026-10-17T07:45:00Z",
+		},
+		{
+			name:      "all-day event",
+			event:     "  start: 17-10-2026\n  end: 18-10-2026\n  timezone: UTC\n",
+			wantStart: "2026-10-17T00:00:00Z",
+			wantEnd:   "2026-10-19T00:00:00Z",
+			wantAll:   true,
+		},
+		{
+			name:    "missing start",
+			event:   "  location: Salle A\n",
+			wantErr: true,
+		},
+		{
+			name:    "end before start",
+			event:   "  start: 17-10-2026 14:00\n  end: 17-10-2026 13:00\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown time zone",
+			event:   "  start: 17-10-2026 14:00\n  timezone: Mars/Olympus\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "invitation.html")
+			content := "---\nsubject: Point projet\nevent:\n" + tt.event + "  location: Salle A\n  organizer: Jane <jane@example.com>\n  attendees: [john@example.com]\n---\n<p>Invitation</p>"
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+
+			rendered, err := RenderTemplate(nil, path, tt.values)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RenderTemplate() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplate() error = %v", err)
+			}
+
+			e := rendered.Event
+			if e == nil {
+				t.Fatalf("Event = nil")
+			}
+			if got := e.Start.UTC().Format(time.RFC3339); got != tt.wantStart {
+				t.Errorf("Start = %s, want %s", got, tt.wantStart)
+			}
+			if got := e.End.UTC().Format(time.RFC3339); got != tt.wantEnd {
+				t.Errorf("End = %s, want %s", got, tt.wantEnd)
+			}
+			if e.AllDay != tt.wantAll {
+				t.Errorf("AllDay = %v, want %v", e.AllDay, tt.wantAll)
+			}
+			if e.Summary != "Point projet" || e.Location != "Salle A" || e.UID == "" {
+				t.Errorf("Event = %+v, want the subject as summary and a UID", e)
+			}
+			if e.Organizer == nil || e.Organizer.Address != "jane@example.com" || len(e.Attendees) != 1 {
+				t.Errorf("organizer = %v, attendees = %v", e.Organizer, e.Attendees)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateEventNotEscaped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invitation.html")
+	content := "---\nsubject: \"Point {{ Project }}\"\nevent:\n  start: 17-10-2026 14:00\n  location: \"{{ Room }}\"\n  description: \"Ordre du jour : {{ Project }}\"\n---\n<p>Invitation</p>"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	rendered, err := RenderTemplate(nil, path, map[string]string{"Project": "R&D", "Room": "Salle Q&A <O'Brien>"})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	e := rendered.Event
+	if e == nil {
+		t.Fatalf("Event = nil")
+	}
+	if e.Summary != "Point R&D" || e.Location != "Salle Q&A <O'Brien>" || e.Description != "Ordre du jour : R&D" {
+		t.Errorf("Event = %+v, want the raw values", e)
+	}
+}
+
+func TestRenderTemplateLayouts(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"layouts/base.html":    "---\ntitle: Layout\n---\n<header>Société</header>{% block content %}{% endblock %}{% include \"partials/footer.html\" %}",
+		"partials/footer.html": "<footer>{{ Signature }}</footer>",
+		"partials/macros.html": "{% macro greet(name) export %}Bonjour {{ name }}{% endmacro %}",
+		"relances/facture.html": "---\nsubject: Relance\n---\n{% extends \"layouts/base.html\" %}" +
+			"{% block content %}{% import \"partials/macros.html\" greet %}<p>{{ greet(\"Jo\") }}, {{ ContactName }}</p>{% endblock %}",
+	}
+	writeTree(t, dir, files)
+	path := filepath.Join(dir, "relances", "facture.html")
+
+	vars, err := ParseTemplate([]string{dir}, path)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if want := []models.TemplateVariable{{Name: "ContactName"}, {Name: "Signature"}}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("ParseTemplate() = %v, want %v", vars, want)
+	}
+
+	rendered, err := RenderTemplate([]string{dir}, path, map[string]string{"ContactName": "Jo", "Signature": "L'équipe"})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	if want := "<header>Société</header><p>Bonjour Jo, Jo</p><footer>L&#39;équipe</footer>"; rendered.HTML != want {
+		t.Errorf("HTML = %q, want %q", rendered.HTML, want)
+	}
+
+	// Without the templates directory, names are relative to the template directory
+	if _, err := RenderTemplate(nil, path, nil); err == nil {
+		t.Errorf("RenderTemplate() error = nil, want an error for a missing layout")
+	}
+}
+
+func TestRenderTemplateSearchPath(t *testing.T) {
+	personal, team, builtin := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTree(t, personal, map[string]string{
+		"layouts/base.html": "<main>{% block content %}{% endblock %}{% include \"partials/footer.html\" %}</main>",
+	})
+	writeTree(t, team, map[string]string{
+		"layouts/base.html":     "<div>{% block content %}{% endblock %}</div>",
+		"relances/facture.html": "{% extends \"layouts/base.html\" %}{% block content %}<p>Bonjour {{ Name }}</p>{% endblock %}",
+	})
+	writeTree(t, builtin, map[string]string{"partials/footer.html": "<footer>{{ Signature }}</footer>"})
+	dirs := []string{personal, team, builtin}
+	path := filepath.Join(team, "relances", "facture.html")
+
+	vars, err := ParseTemplate(dirs, path)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if want := []models.TemplateVariable{{Name: "Name"}, {Name: "Signature"}}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("ParseTemplate() = %v, want %v", vars, want)
+	}
+
+	rendered, err := RenderTemplate(dirs, path, map[string]string{"Name": "Jo", "Signature": "Bob"})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	if want := "<main><p>Bonjour Jo</p><footer>Bob</footer></main>"; rendered.HTML != want {
+		t.Errorf("HTML = %q, want the layout of the first directory holding it, %q", rendered.HTML, want)
+	}
+}
+
+func TestRenderTemplateMarkdown(t *testing.T) {
+	body := "---\nsubject: Relance {{ Invoice }}\n%s---\nBonjour **{{ Name }}**,\nMerci.\n\n| Facture | Montant |\n|---|---|\n| {{ Invoice }} | 42 € |\n\n- [Portail](https://example.com)\n"
+	wantContent := "<p>Bonjour <strong>Jo &amp; Co</strong>,<br>\nMerci.</p>\n<table>\n<thead>\n<tr>\n<th>Facture</th>\n<th>Montant</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>F-1</td>\n<td>42 €</td>\n</tr>\n</tbody>\n</table>\n<ul>\n<li><a href=\"https://example.com\">Portail</a></li>\n</ul>\n"
+
+	tests := []struct {
+		name     string
+		layout   string
+		files    map[string]string
+		wantVars []string
+		want     string
+	}{
+		{
+			name:     "built-in layout",
+			wantVars: []string{"Invoice", "Name"},
+			want:     "<body>\n" + wantContent + "\n</body>",
+		},
+		{
+			name:     "default layout of the templates directory",
+			files:    map[string]string{"layouts/markdown.html": "---\ntitle: Layout\n---\n<div>{{ content }}</div>{{ Signature }}"},
+			wantVars: []string{"Invoice", "Name", "Signature"},
+			want:     "<div>" + wantContent + "</div>Bob",
+		},
+		{
+			name:   "layout from the frontmatter",
+			layout: "layout: layouts/client.html\n",
+			files: map[string]string{
+				"layouts/base.html":   "<main>{% block content %}{% endblock %}</main>",
+				"layouts/client.html": "{% extends \"layouts/base.html\" %}{% block content %}{{ content }}{% endblock %}",
+			},
+			wantVars: []string{"Invoice", "Name"},
+			want:     "<main>" + wantContent + "</main>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+			path := filepath.Join(dir, "relance.md")
+			if err := os.WriteFile(path, []byte(fmt.Sprintf(body, tt.layout)), 0o600); err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+
+			vars, err := ParseTemplate([]string{dir}, path)
+			if err != nil {
+				t.Fatalf("ParseTemplate() error = %v", err)
+			}
+			var names []string
+			for _, v := range vars {
+				names = append(names, v.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantVars) {
+				t.Errorf("ParseTemplate() = %v, want %v", names, tt.wantVars)
+			}
+
+			rendered, err := RenderTemplate([]string{dir}, path, map[string]string{"Invoice": "F-1", "Name": "Jo & Co", "Signature": "Bob"})
+			if err != nil {
+				t.Fatalf("RenderTemplate() error = %v", err)
+			}
+			if !strings.Contains(rendered.HTML, tt.want) {
+				t.Errorf("HTML = %q, want %q", rendered.HTML, tt.want)
+			}
+			if rendered.Subject != "Relance F-1" || !strings.Contains(rendered.Text, "Jo & Co") {
+				t.Errorf("Subject = %q, Text = %q", rendered.Subject, rendered.Text)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateLayoutImages(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"layouts/base.html":      `<img src="assets/logo.png">{% block content %}{% endblock %}`,
+		"assets/logo.png":        "png",
+		"relances/facture.html":  `{% extends "layouts/base.html" %}{% block content %}<img src="signature.png">{% endblock %}`,
+		"relances/signature.png": "png",
+	}
+	writeTree(t, dir, files)
+
+	rendered, err := RenderTemplate([]string{dir}, filepath.Join(dir, "relances", "facture.html"), nil)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	var paths []string
+	for _, a := range rendered.Attachments {
+		paths = append(paths, a.Path)
+	}
+	want := []string{filepath.Join(dir, "assets", "logo.png"), filepath.Join(dir, "relances", "signature.png")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("inline images = %v, want %v", paths, want)
+	}
+}
@@ -2,8 +2,10 @@ package templates
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"mailmate/internal/models"
+	"mailmate/internal/plaintext"
 	"mailmate/internal/validator"
 
 	"github.com/flosch/pongo2/v6"
@@ -72,7 +74,10 @@ func filterInt(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Er
 } */
 
 // RenderTemplate renders the template at the given path using the provided variables.
-func RenderTemplate(tmplPath string, variables map[string]string) (*models.RenderedTemplate, error) {
+// The layouts, partials and macros used by {% extends %}, {% include %} and {% import %}
+// are looked up in the templates directories dirs, in order of precedence, or in the directory
+// of the template if dirs is empty. So are the images they reference, after the template directory.
+func RenderTemplate(dirs []string, tmplPath string, variables map[string]string) (*models.RenderedTemplate, error) {
 	// Parse the template file to separate frontmatter (subject) and body.
 	parsed, err := ParseTemplateFile(tmplPath)
 	if err != nil {
@@ -85,9 +90,13 @@ func RenderTemplate(tmplPath string, variables map[string]string) (*models.Rende
 		ctx[k] = v
 	}
 
+	// Template set resolving the layouts and partials of the templates directory
+	roots := templatesRoots(dirs, tmplPath)
+	set := newTemplateSet(roots)
+
 	// 1. Render the Body
 	// We use FromString because we have already read and stripped the frontmatter.
-	bodyTpl, err := pongo2.FromString(parsed.Body)
+	bodyTpl, err := set.FromString(parsed.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template body for %q: %w", tmplPath, err)
 	}
@@ -97,62 +106,172 @@ func RenderTemplate(tmplPath string, variables map[string]string) (*models.Rende
 		return nil, fmt.Errorf("failed to render template body for %q: %w", tmplPath, err)
 	}
 
-	// 2. Render the Subject
-	// The subject might also contain variables.
-	subjectTpl, err := pongo2.FromString(parsed.Subject)
+	// Markdown templates are converted to HTML and wrapped in their layout
+	if IsMarkdown(tmplPath) {
+		bodyOut, err = renderMarkdown(set, roots, parsed.Layout, bodyOut, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render markdown template %q: %w", tmplPath, err)
+		}
+	}
+
+	baseDir, err := filepath.Abs(filepath.Dir(tmplPath))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse template subject for %q: %w", tmplPath, err)
+		return nil, fmt.Errorf("resolving template directory for %q: %w", tmplPath, err)
 	}
 
-	subjectOut, err := subjectTpl.Execute(ctx)
+	// Resolve the files attached by the frontmatter
+	files, err := resolveAttachments(parsed.Attachments, baseDir, ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to render template subject for %q: %w", tmplPath, err)
+		return nil, fmt.Errorf("template %q: %w", tmplPath, err)
 	}
 
-	// 3. Render the recipients (To, Cc, Bcc)
-	// These might also contain variables.
-	var toOut, ccOut, bccOut string
+	// Embed the local images referenced by the body as inline attachments
+	bodyOut, images, err := EmbedImages(bodyOut, append([]string{baseDir}, roots...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed images for %q: %w", tmplPath, err)
+	}
 
-	if parsed.To != "" {
-		toTpl, err := pongo2.FromString(parsed.To)
+	// Render the hand-written text body, possibly inherited from a layout, or generate it from the HTML
+	inherited, err := inheritedText(roots, parsed.Body, wrappingLayouts(roots, tmplPath, parsed.Layout)...)
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", tmplPath, err)
+	}
+	if parsed.Text == "" {
+		parsed.Text = inherited
+	}
+	textOut := ""
+	if parsed.Text != "" {
+		// Plain text must not be HTML-escaped
+		textTpl, err := set.FromString("{% autoescape off %}" + parsed.Text + "{% endautoescape %}")
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse template 'to' field for %q: %w", tmplPath, err)
+			return nil, fmt.Errorf("failed to parse template text body for %q: %w", tmplPath, err)
 		}
-		toOut, err = toTpl.Execute(ctx)
+		textOut, err = textTpl.Execute(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to render template 'to' field for %q: %w", tmplPath, err)
+			return nil, fmt.Errorf("failed to render template text body for %q: %w", tmplPath, err)
 		}
+	} else {
+		textOut = plaintext.FromHTML(bodyOut)
 	}
 
-	if parsed.Cc != "" {
-		ccTpl, err := pongo2.FromString(parsed.Cc)
+	// 2. Render the Subject
+	// The subject might also contain variables.
+	subjectOut, err := renderField(parsed.Subject, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template subject for %q: %w", tmplPath, err)
+	}
+
+	// 3. Render the recipients (To, Cc, Bcc) and the other frontmatter fields
+	// These might also contain variables.
+	fields := []struct{ name, value string }{
+		{"to", parsed.To},
+		{"cc", parsed.Cc},
+		{"bcc", parsed.Bcc},
+		{"from", parsed.From},
+		{"reply_to", parsed.ReplyTo},
+		{"importance", parsed.Importance},
+		{"sensitivity", parsed.Sensitivity},
+		{"read_receipt", parsed.ReadReceipt},
+		{"delivery_receipt", parsed.DeliveryReceipt},
+	}
+	rendered := make(map[string]string, len(fields))
+	for _, f := range fields {
+		out, err := renderField(f.value, ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse template 'cc' field for %q: %w", tmplPath, err)
+			return nil, fmt.Errorf("failed to render template '%s' field for %q: %w", f.name, tmplPath, err)
 		}
-		ccOut, err = ccTpl.Execute(ctx)
+		rendered[f.name] = out
+	}
+
+	// 4. Render and validate the message options
+	importance, err := parseImportance(rendered["importance"])
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", tmplPath, err)
+	}
+	sensitivity, err := parseSensitivity(rendered["sensitivity"])
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", tmplPath, err)
+	}
+	readReceipt, err := parseFlag("read_receipt", rendered["read_receipt"])
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", tmplPath, err)
+	}
+	deliveryReceipt, err := parseFlag("delivery_receipt", rendered["delivery_receipt"])
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", tmplPath, err)
+	}
+
+	var event *models.Event
+	if parsed.Event != nil {
+		event, err = renderEvent(parsed.Event, ctx, subjectOut)
 		if err != nil {
-			return nil, fmt.Errorf("failed to render template 'cc' field for %q: %w", tmplPath, err)
+			return nil, fmt.Errorf("template %q: %w", tmplPath, err)
 		}
 	}
 
-	if parsed.Bcc != "" {
-		bccTpl, err := pongo2.FromString(parsed.Bcc)
+	var headers map[string]string
+	for name, value := range parsed.Headers {
+		out, err := renderField(value, ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse template 'bcc' field for %q: %w", tmplPath, err)
+			return nil, fmt.Errorf("failed to render template header %q for %q: %w", name, tmplPath, err)
 		}
-		bccOut, err = bccTpl.Execute(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to render template 'bcc' field for %q: %w", tmplPath, err)
+		if err := validateHeaderValue(name, out); err != nil {
+			return nil, fmt.Errorf("template %q: %w", tmplPath, err)
 		}
+		if headers == nil {
+			headers = make(map[string]string, len(parsed.Headers))
+		}
+		headers[name] = out
 	}
 
 	return &models.RenderedTemplate{
-		Subject: subjectOut,
-		HTML:    bodyOut,
-		To:      toOut,
-		Cc:      ccOut,
-		Bcc:     bccOut,
+		Subject:         subjectOut,
+		HTML:            bodyOut,
+		Text:            textOut,
+		To:              rendered["to"],
+		Cc:              rendered["cc"],
+		Bcc:             rendered["bcc"],
+		From:            rendered["from"],
+		ReplyTo:         rendered["reply_to"],
+		Importance:      importance,
+		Sensitivity:     sensitivity,
+		ReadReceipt:     readReceipt,
+		DeliveryReceipt: deliveryReceipt,
+		Headers:         headers,
+		Event:           event,
+		Files:           files,
+		Attachments:     images,
+		PostProcess:     parsed.PostProcess,
 	}, nil
 }
 
+// templatesRoots returns the absolute templates directories of the template at path:
+// dirs if set, else the directory of the template.
+func templatesRoots(dirs []string, path string) []string {
+	if len(dirs) == 0 {
+		dirs = []string{filepath.Dir(path)}
+	}
+	roots := make([]string, len(dirs))
+	for i, dir := range dirs {
+		roots[i] = dir
+		if abs, err := filepath.Abs(dir); err == nil {
+			roots[i] = abs
+		}
+	}
+	return roots
+}
+
+// renderField renders a frontmatter field with the template variables.
+// Fields are not HTML, so the values are not escaped. Empty fields are returned as is.
+func renderField(value string, ctx pongo2.Context) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	tpl, err := pongo2.FromString("{% autoescape off %}" + value + "{% endautoescape %}")
+	if err != nil {
+		return "", err
+	}
+	return tpl.Execute(ctx)
+}
+
 //TODO : maybe I can build a package to generate filters for render in same object has form checker for tui, it would export the required function that iwll be imported by form.go and render.go
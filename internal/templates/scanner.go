@@ -1,38 +1,155 @@
 package templates
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"mailmate/internal/models"
 )
 
-// ScanTemplates searches for .html files in the specified directory.
-// It returns a list of found templates or an error if the directory is missing or empty.
-func ScanTemplates(dir string) ([]models.TemplateRef, error) {
-	entries, err := os.ReadDir(dir)
+// IgnoredDirs are the directories of the templates directory that hold no templates,
+// but files used by them (layouts, partials, images, stylesheets).
+// Directories whose name starts with "." or "_" are ignored as well.
+var IgnoredDirs = []string{"layouts", "partials", "assets"}
+
+// ScanTemplates searches for .html and Markdown (.md) files, except READMEs, in the specified directories and their subdirectories.
+// The directories form a search path: a template of an earlier directory overrides the template
+// with the same relative path (e.g. "relances/facture.html") in the later ones.
+// Directories that do not exist are skipped, as long as one of them exists.
+// Subdirectories are categories: "relances/facture.html" is in the "relances" category.
+// Templates are sorted by category, top-level templates first, then by name,
+// and carry the metadata of their frontmatter (title, description, tags, owner, language).
+// It returns a list of found templates or an error if the directories are missing or empty.
+func ScanTemplates(dirs ...string) ([]models.TemplateRef, error) {
+	var templates []models.TemplateRef
+	seen := make(map[string]bool)
+	var missing error
+	exists := false
+	for _, dir := range dirs {
+		found, err := scanDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			if missing == nil {
+				missing = err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		exists = true
+		for _, tmpl := range found {
+			key := path.Join(tmpl.Category, tmpl.Name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			templates = append(templates, tmpl)
+		}
+	}
+	if !exists && missing != nil {
+		return nil, missing
+	}
+
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("no templates found in %q", strings.Join(dirs, string(filepath.ListSeparator)))
+	}
+
+	sort.SliceStable(templates, func(i, j int) bool {
+		if templates[i].Category != templates[j].Category {
+			return templates[i].Category < templates[j].Category
+		}
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// scanDir returns the templates of the templates directory dir.
+func scanDir(dir string) ([]models.TemplateRef, error) {
+	info, err := os.Stat(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("templates directory not found at %q: %w", dir, err)
 		}
 		return nil, fmt.Errorf("failed to read templates directory: %w", err)
 	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("templates path %q is not a directory", dir)
+	}
 
 	var templates []models.TemplateRef
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(strings.ToLower(entry.Name()), ".html") {
-			templates = append(templates, models.TemplateRef{
-				Name: entry.Name(),
-				Path: filepath.Join(dir, entry.Name()),
-			})
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && isIgnoredDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
 		}
+		if !isTemplateFile(entry.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		category := filepath.ToSlash(rel)
+		if category == "." {
+			category = ""
+		}
+		ref := models.TemplateRef{
+			Name:     entry.Name(),
+			Path:     path,
+			Category: category,
+			Root:     dir,
+		}
+		// A malformed frontmatter is reported when the template is used, not when listing it
+		if parsed, err := parseFrontmatter(path); err == nil {
+			ref.Title = parsed.Title
+			ref.Description = parsed.Description
+			ref.Tags = parsed.Tags
+			ref.Owner = parsed.Owner
+			ref.Language = parsed.Language
+		}
+		templates = append(templates, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
 	}
+	return templates, nil
+}
 
-	if len(templates) == 0 {
-		return nil, fmt.Errorf("no templates found in %q", dir)
+// isIgnoredDir reports whether the directory named name holds no templates.
+func isIgnoredDir(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return true
+	}
+	for _, ignored := range IgnoredDirs {
+		if strings.EqualFold(name, ignored) {
+			return true
+		}
 	}
+	return false
+}
 
-	return templates, nil
+// isTemplateFile reports whether the file name designates a template: an .html or .md file.
+// README files document the templates directory and are not templates.
+func isTemplateFile(name string) bool {
+	lower := strings.ToLower(name)
+	switch filepath.Ext(lower) {
+	case ".html":
+		return true
+	case ".md":
+		return !strings.HasPrefix(lower, "readme")
+	}
+	return false
 }
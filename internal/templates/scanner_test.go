@@ -0,0 +1,138 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mailmate/internal/models"
+)
+
+func TestScanTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := make(map[string]string)
+	for _, name := range []string{
+		"rapport.html",
+		"Accueil.HTML",
+		"guide.md",
+		"README.md",
+		"relances/Readme.fr.md",
+		"notes.txt",
+		"relances/facture.html",
+		"relances/clients/retard.html",
+		"invitations/reunion.html",
+		"layouts/base.html",
+		"partials/footer.html",
+		"assets/banner.html",
+		".git/index.html",
+		"_brouillons/test.html",
+	} {
+		files[name] = "<p>Bonjour</p>"
+	}
+	writeTree(t, dir, files)
+
+	got, err := ScanTemplates(dir)
+	if err != nil {
+		t.Fatalf("ScanTemplates() error = %v", err)
+	}
+	want := []models.TemplateRef{
+		{Name: "Accueil.HTML", Path: filepath.Join(dir, "Accueil.HTML"), Root: dir},
+		{Name: "guide.md", Path: filepath.Join(dir, "guide.md"), Root: dir},
+		{Name: "rapport.html", Path: filepath.Join(dir, "rapport.html"), Root: dir},
+		{Name: "reunion.html", Path: filepath.Join(dir, "invitations", "reunion.html"), Root: dir, Category: "invitations"},
+		{Name: "facture.html", Path: filepath.Join(dir, "relances", "facture.html"), Root: dir, Category: "relances"},
+		{Name: "retard.html", Path: filepath.Join(dir, "relances", "clients", "retard.html"), Root: dir, Category: "relances/clients"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanTemplates() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestScanTemplatesErrors(t *testing.T) {
+	if _, err := ScanTemplates(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("ScanTemplates() error = nil for a missing directory")
+	}
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"partials/footer.html": ""})
+	if _, err := ScanTemplates(dir); err == nil {
+		t.Errorf("ScanTemplates() error = nil for a directory with partials only")
+	}
+}
+
+func TestScanTemplatesMetadata(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"facture.html": "---\ntitle: Relance facture\ndescription: Première relance d'une facture impayée\ntags: [relance, compta]\nowner: Équipe compta\nlanguage: fr\nsubject: Relance\n---\n<p>Bonjour</p>",
+		"devis.html":   "---\ntitle: Devis\ntags: \"devis, commercial\"\n---\n<p>Bonjour</p>",
+		"casse.html":   "---\ntitle: [\n---\n<p>Bonjour</p>",
+	}
+	writeTree(t, dir, files)
+
+	got, err := ScanTemplates(dir)
+	if err != nil {
+		t.Fatalf("ScanTemplates() error = %v", err)
+	}
+	want := []models.TemplateRef{
+		{Name: "casse.html", Path: filepath.Join(dir, "casse.html"), Root: dir},
+		{Name: "devis.html", Path: filepath.Join(dir, "devis.html"), Root: dir, Title: "Devis", Tags: []string{"devis", "commercial"}},
+		{
+			Name:        "facture.html",
+			Path:        filepath.Join(dir, "facture.html"),
+			Root:        dir,
+			Title:       "Relance facture",
+			Description: "Première relance d'une facture impayée",
+			Tags:        []string{"relance", "compta"},
+			Owner:       "Équipe compta",
+			Language:    "fr",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanTemplates() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got[0].DisplayName() != "casse.html" || got[1].DisplayName() != "Devis" {
+		t.Errorf("DisplayName() = %q, %q", got[0].DisplayName(), got[1].DisplayName())
+	}
+}
+
+func TestScanTemplatesSearchPath(t *testing.T) {
+	personal, team := t.TempDir(), t.TempDir()
+	writeTree(t, personal, map[string]string{"relances/facture.html": "<p>Bonjour</p>"})
+	writeTree(t, team, map[string]string{
+		"relances/facture.html": "<p>Bonjour</p>",
+		"relances/devis.html":   "<p>Bonjour</p>",
+		"rapport.html":          "<p>Bonjour</p>",
+	})
+
+	got, err := ScanTemplates(personal, filepath.Join(t.TempDir(), "offline"), team)
+	if err != nil {
+		t.Fatalf("ScanTemplates() error = %v", err)
+	}
+	want := []models.TemplateRef{
+		{Name: "rapport.html", Path: filepath.Join(team, "rapport.html"), Root: team},
+		{Name: "devis.html", Path: filepath.Join(team, "relances", "devis.html"), Root: team, Category: "relances"},
+		{Name: "facture.html", Path: filepath.Join(personal, "relances", "facture.html"), Root: personal, Category: "relances"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanTemplates() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if _, err := ScanTemplates(filepath.Join(t.TempDir(), "a"), filepath.Join(t.TempDir(), "b")); err == nil {
+		t.Errorf("ScanTemplates() error = nil, want an error when no directory exists")
+	}
+}
+
+// writeTree writes files, keyed by their slash-separated path relative to root, creating their directories.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/huh"
+
+	"mailmate/internal/validator"
+)
+
+// CollectAttachments optionally prompts the user for extra files to attach.
+// The user is asked whether to add a file until they decline; declining first returns no files.
+func CollectAttachments() ([]string, error) {
+	var paths []string
+
+	for {
+		title := "Add an attachment?"
+		if len(paths) > 0 {
+			title = "Add another attachment?"
+		}
+
+		add := false
+		confirm := huh.NewForm(huh.NewGroup(
+			huh.NewConfirm().
+				Title(title).
+				Value(&add),
+		))
+		if err := confirm.Run(); err != nil {
+			return nil, fmt.Errorf("form cancelled/error: %w", err)
+		}
+		if !add {
+			return paths, nil
+		}
+
+		var path string
+		input := huh.NewForm(huh.NewGroup(
+			huh.NewInput().
+				Title("Attachment").
+				Placeholder("/path/to/file").
+				Value(&path).
+				Validate(func(s string) error {
+					_, err := validator.ValidateAttachment(s)
+					return err
+				}),
+		))
+		if err := input.Run(); err != nil {
+			return nil, fmt.Errorf("form cancelled/error: %w", err)
+		}
+		paths = append(paths, path)
+	}
+}
@@ -3,52 +3,140 @@ package tui
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/huh"
 
 	"mailmate/internal/models"
 )
 
+const (
+	// categoryPrefix marks the option values that open a category rather than select a template.
+	categoryPrefix = "category:"
+	// backValue is the option value going back to the category list.
+	backValue = "back:"
+	// selectHeight bounds the height of long lists, which then scroll.
+	selectHeight = 15
+)
+
 // SelectTemplate prompts the user to select a template from the provided list.
+// Templates in subdirectories are grouped by category: the first list shows the categories
+// and the top-level templates, and choosing a category lists its templates.
 // It returns the selected TemplateRef or an error if selection fails/is cancelled.
 func SelectTemplate(templates []models.TemplateRef) (*models.TemplateRef, error) {
 	if len(templates) == 0 {
 		return nil, errors.New("no templates available to select")
 	}
 
-	// Create options for huh.Select
-	options := make([]huh.Option[string], len(templates))
-	// Map to look up the full struct by Name (since Select returns the value, which we'll use as the Name for uniqueness)
-	// Alternatively, we can use the Path as the value. Let's use Path as value to be safe if names collide (though scanner should handle that).
-	// But to return the original struct, a map is easy.
+	// Map to look up the full struct by Path, which is used as the option value since names may collide across categories.
 	templateMap := make(map[string]models.TemplateRef)
-
-	for i, tmpl := range templates {
-		options[i] = huh.NewOption(tmpl.Name, tmpl.Path)
+	var categories []string
+	byCategory := make(map[string][]models.TemplateRef)
+	// With a search path of several directories, labels show the directory of each template
+	showSource := false
+	for _, tmpl := range templates {
 		templateMap[tmpl.Path] = tmpl
+		showSource = showSource || tmpl.Root != templates[0].Root
+		if _, ok := byCategory[tmpl.Category]; !ok && tmpl.Category != "" {
+			categories = append(categories, tmpl.Category)
+		}
+		byCategory[tmpl.Category] = append(byCategory[tmpl.Category], tmpl)
+	}
+
+	// The description of the hovered template is shown under the list title
+	describe := func(value string) string {
+		tmpl, ok := templateMap[value]
+		if !ok {
+			return ""
+		}
+		return templateDetails(tmpl)
 	}
 
-	var selectedPath string
+	for {
+		// Categories first, then top-level templates
+		var options []huh.Option[string]
+		for _, c := range categories {
+			label := fmt.Sprintf("%s/ (%d)", c, len(byCategory[c]))
+			options = append(options, huh.NewOption(label, categoryPrefix+c))
+		}
+		for _, tmpl := range byCategory[""] {
+			options = append(options, huh.NewOption(templateLabel(tmpl, showSource), tmpl.Path))
+		}
 
-	// Create the form with a Select field
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewSelect[string]().
-				Title("Select a template").
-				Options(options...).
-				Value(&selectedPath),
-		),
-	)
+		selectedPath, err := selectOption("Select a template", options, describe)
+		if err != nil {
+			return nil, err
+		}
 
-	err := form.Run()
-	if err != nil {
-		return nil, fmt.Errorf("template selection failed: %w", err)
+		if category, ok := strings.CutPrefix(selectedPath, categoryPrefix); ok {
+			options = []huh.Option[string]{huh.NewOption("← Back", backValue)}
+			for _, tmpl := range byCategory[category] {
+				options = append(options, huh.NewOption(templateLabel(tmpl, showSource), tmpl.Path))
+			}
+			selectedPath, err = selectOption("Select a template in "+category, options, describe)
+			if err != nil {
+				return nil, err
+			}
+			if selectedPath == backValue {
+				continue
+			}
+		}
+
+		selected, ok := templateMap[selectedPath]
+		if !ok {
+			return nil, errors.New("selected template not found in map")
+		}
+		return &selected, nil
 	}
+}
 
-	selected, ok := templateMap[selectedPath]
-	if !ok {
-		return nil, errors.New("selected template not found in map")
+// selectOption runs a form with a single Select field and returns the selected value.
+// describe returns the description of the hovered value.
+func selectOption(title string, options []huh.Option[string], describe func(value string) string) (string, error) {
+	var value string
+	field := huh.NewSelect[string]().
+		Title(title).
+		DescriptionFunc(func() string { return describe(value) }, &value).
+		Options(options...).
+		Value(&value)
+	if len(options) > selectHeight {
+		field = field.Height(selectHeight)
 	}
+	form := huh.NewForm(huh.NewGroup(field))
+	if err := form.Run(); err != nil {
+		return "", fmt.Errorf("template selection failed: %w", err)
+	}
+	return value, nil
+}
 
-	return &selected, nil
+// templateLabel returns the label of a template in the list: its title, language and tags,
+// and the templates directory it comes from if showSource is set.
+func templateLabel(t models.TemplateRef, showSource bool) string {
+	label := t.DisplayName()
+	if t.Language != "" {
+		label += " [" + t.Language + "]"
+	}
+	if len(t.Tags) > 0 {
+		label += " · " + strings.Join(t.Tags, ", ")
+	}
+	if showSource {
+		label += " (" + t.Root + ")"
+	}
+	return label
+}
+
+// templateDetails returns the description, owner, source directory and file of a template.
+func templateDetails(t models.TemplateRef) string {
+	var lines []string
+	if t.Description != "" {
+		lines = append(lines, t.Description)
+	}
+	if t.Owner != "" {
+		lines = append(lines, "Owner: "+t.Owner)
+	}
+	if t.Root != "" {
+		lines = append(lines, "Source: "+t.Root)
+	}
+	lines = append(lines, "File: "+t.Path)
+	return strings.Join(lines, "\n")
 }
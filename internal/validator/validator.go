@@ -52,6 +52,32 @@ func ValidateFileExists(value string) error {
 	return nil
 }
 
+// MaxAttachmentSize is the largest attachment size accepted, in bytes.
+// It matches the default message size limit of Outlook and most mail providers.
+const MaxAttachmentSize = 20 * 1024 * 1024
+
+// ValidateAttachment checks that the file exists, is a regular file and is not larger than MaxAttachmentSize.
+// It returns the file size if valid, or an error if not.
+func ValidateAttachment(value string) (int64, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, fmt.Errorf("attachment path cannot be empty")
+	}
+	info, err := os.Stat(value)
+	if os.IsNotExist(err) {
+		return 0, fmt.Errorf("file %q does not exist", value)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot read file %q: %w", value, err)
+	}
+	if !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("%q is not a regular file", value)
+	}
+	if info.Size() > MaxAttachmentSize {
+		return 0, fmt.Errorf("file %q is too large (%d MB, max %d MB)", value, info.Size()>>20, MaxAttachmentSize>>20)
+	}
+	return info.Size(), nil
+}
+
 // GetFilename returns the base name of a filepath.
 // Useful for displaying just the filename in templates instead of full path.
 func GetFilename(value string) string {